@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+)
+
+// ReleaseTimesPath is the path of the endpoint that the publishing tooling sends release times to
+const ReleaseTimesPath = "/admin/release-times"
+
+// ReleaseTimeCacheStatsPath is the path of the endpoint that reports the usage of the release time cache
+const ReleaseTimeCacheStatsPath = "/admin/release-time-cache/stats"
+
+// ClockPath is the path of the endpoint that shifts the clock, which is only added if time travel is enabled
+const ClockPath = "/admin/clock"
+
+// Setup adds the admin endpoints to the given router, which must be added before the proxy's catch-all route. Every
+// admin endpoint requires the admin auth token in the configuration.
+func Setup(ctx context.Context, r *mux.Router, cfg *config.Config, store *response.ReleaseTimeStore, cache releaseTimeCache, clock *response.Clock) {
+	r.Path(ReleaseTimesPath).
+		Methods(http.MethodPost).
+		Handler(requireAuthToken(cfg.AdminAuthToken, ReleaseTimesHandler(store, cache))).
+		Name("Admin Release Times")
+
+	r.Path(ReleaseTimeCacheStatsPath).
+		Methods(http.MethodGet).
+		Handler(requireAuthToken(cfg.AdminAuthToken, ReleaseTimeCacheStatsHandler(cache))).
+		Name("Admin Release Time Cache Stats")
+
+	log.Info(ctx, "admin endpoints enabled", log.Data{"paths": []string{ReleaseTimesPath, ReleaseTimeCacheStatsPath}})
+
+	if clock.TimeTravelEnabled() {
+		r.Path(ClockPath).
+			Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+			Handler(requireAuthToken(cfg.AdminAuthToken, ClockHandler(clock))).
+			Name("Admin Clock")
+
+		log.Warn(ctx, "time travel enabled", log.Data{"path": ClockPath, "environment": cfg.Environment})
+	}
+}
+
+// requireAuthToken rejects any request that does not have the given token as its bearer token
+func requireAuthToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authToken, isBearer := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !isBearer || subtle.ConstantTimeCompare([]byte(authToken), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
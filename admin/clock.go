@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+const maxClockBodySize = 1 << 10
+
+// ClockRequest is the body of a request to shift the clock
+type ClockRequest struct {
+	Now time.Time `json:"now"`
+}
+
+// ClockResponse is the body of the response of the clock endpoint
+type ClockResponse struct {
+	Now    time.Time `json:"now"`
+	Offset string    `json:"offset"`
+}
+
+// ClockHandler shows the time that the cache headers are calculated from, shifts it to the time in the request's body
+// (PUT) or sets it back to the real time (DELETE), so that a release can be rehearsed
+func ClockHandler(clock *response.Clock) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		w.Header().Set("Cache-Control", "no-store")
+
+		switch req.Method {
+		case http.MethodPut:
+			var body ClockRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxClockBodySize)).Decode(&body); err != nil {
+				http.Error(w, "invalid clock: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if body.Now.IsZero() {
+				http.Error(w, "invalid clock: now must be set", http.StatusBadRequest)
+				return
+			}
+
+			clock.TravelTo(body.Now)
+			log.Warn(ctx, "clock shifted", log.Data{"now": body.Now, "offset": clock.Offset().String()})
+		case http.MethodDelete:
+			clock.Reset()
+			log.Info(ctx, "clock reset")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ClockResponse{Now: clock.Current(), Offset: clock.Offset().String()}); err != nil {
+			log.Error(ctx, "error writing the clock response", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClockHandler(t *testing.T) {
+	Convey("Given the admin endpoints with time travel enabled", t, func() {
+		ctx := context.Background()
+		cfg := &config.Config{AdminAuthToken: testAuthToken, ReleaseTimeStoreTTL: time.Hour, Environment: "staging", EnableTimeTravel: true}
+		clock, err := response.NewClock(cfg)
+		So(err, ShouldBeNil)
+		router := mux.NewRouter()
+		Setup(ctx, router, cfg, response.NewReleaseTimeStore(cfg), &stubEvicter{}, clock)
+
+		sendClockRequest := func(method, authorization, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, ClockPath, strings.NewReader(body))
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		Convey("When the clock is shifted with the auth token", func() {
+			rehearsal := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+			w := sendClockRequest(http.MethodPut, "Bearer "+testAuthToken, `{"now": "`+rehearsal.Format(time.RFC3339)+`"}`)
+
+			Convey("Then the shifted time is returned and used by the clock", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
+				So(w.Header().Get("Cache-Control"), ShouldEqual, "no-store")
+
+				var body ClockResponse
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body.Now, ShouldHappenWithin, 2*time.Second, rehearsal)
+				So(clock.Current(), ShouldHappenWithin, 2*time.Second, rehearsal)
+				So(clock.Offset(), ShouldBeGreaterThan, 23*time.Hour)
+			})
+
+			Convey("And then reset", func() {
+				w = sendClockRequest(http.MethodDelete, "Bearer "+testAuthToken, "")
+
+				Convey("Then the clock is back to the real time", func() {
+					So(w.Code, ShouldEqual, http.StatusOK)
+					So(clock.Offset(), ShouldEqual, 0)
+					So(w.Body.String(), ShouldContainSubstring, `"offset":"0s"`)
+				})
+			})
+		})
+
+		Convey("When the clock is shifted with an invalid body", func() {
+			for _, body := range []string{"", `{"now": "tomorrow"}`, `{}`} {
+				w := sendClockRequest(http.MethodPut, "Bearer "+testAuthToken, body)
+
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			}
+
+			Convey("Then the clock is not shifted", func() {
+				So(clock.Offset(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the clock is shifted without the right auth token", func() {
+			w := sendClockRequest(http.MethodPut, "Bearer wrong-token", `{"now": "2024-02-15T09:29:30Z"}`)
+
+			Convey("Then the request is rejected and the clock is not shifted", func() {
+				So(w.Code, ShouldEqual, http.StatusUnauthorized)
+				So(clock.Offset(), ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given the admin endpoints with time travel disabled", t, func() {
+		ctx := context.Background()
+		cfg := &config.Config{AdminAuthToken: testAuthToken, ReleaseTimeStoreTTL: time.Hour}
+		clock, err := response.NewClock(cfg)
+		So(err, ShouldBeNil)
+		router := mux.NewRouter()
+		Setup(ctx, router, cfg, response.NewReleaseTimeStore(cfg), &stubEvicter{}, clock)
+
+		Convey("When the clock endpoint is called", func() {
+			req := httptest.NewRequest(http.MethodGet, ClockPath, http.NoBody)
+			req.Header.Set("Authorization", "Bearer "+testAuthToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			Convey("Then it is not found", func() {
+				So(w.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+}
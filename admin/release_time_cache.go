@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// releaseTimeCache is the cache of release times, which must be looked up again once they have been updated and
+// reports its usage
+type releaseTimeCache interface {
+	releaseTimeEvicter
+	Stats() response.ReleaseTimeCacheStats
+}
+
+// ReleaseTimeCacheStatsHandler returns the number of entries in the release time cache and its hits and misses
+func ReleaseTimeCacheStatsHandler(cache releaseTimeCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(cache.Stats()); err != nil {
+			log.Error(req.Context(), "error writing the release time cache stats response", err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReleaseTimeCacheStatsHandler(t *testing.T) {
+	Convey("Given the admin endpoints", t, func() {
+		ctx := context.Background()
+		cfg := &config.Config{AdminAuthToken: testAuthToken, ReleaseTimeStoreTTL: time.Hour}
+		clock, err := response.NewClock(cfg)
+		So(err, ShouldBeNil)
+		router := mux.NewRouter()
+		Setup(ctx, router, cfg, response.NewReleaseTimeStore(cfg), &stubEvicter{}, clock)
+
+		getStats := func(authorization string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, ReleaseTimeCacheStatsPath, http.NoBody)
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		Convey("When the stats are requested with the auth token", func() {
+			w := getStats("Bearer " + testAuthToken)
+
+			Convey("Then the usage of the release time cache is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
+				So(w.Header().Get("Cache-Control"), ShouldEqual, "no-store")
+
+				var body response.ReleaseTimeCacheStats
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body, ShouldResemble, response.ReleaseTimeCacheStats{Entries: 2, Hits: 5, StaleHits: 1, Misses: 2})
+			})
+		})
+
+		Convey("When the stats are requested without the auth token", func() {
+			w := getStats("")
+
+			Convey("Then the request is unauthorised", func() {
+				So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+	})
+}
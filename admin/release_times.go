@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+const maxReleaseTimesBodySize = 1 << 20
+
+// releaseTimeEvicter is anything holding release times that must be looked up again once they have been updated
+type releaseTimeEvicter interface {
+	Evict(ctx context.Context, uri string) error
+}
+
+// ReleaseTimesResponse is the body of the response of the release times endpoint. NotEvicted lists the paths whose
+// previous release time could not be evicted from the cache, so it may still be used until it expires.
+type ReleaseTimesResponse struct {
+	Added      int      `json:"added"`
+	Updated    int      `json:"updated"`
+	NotEvicted []string `json:"not_evicted,omitempty"`
+}
+
+// ReleaseTimesHandler stores a batch of release times sent by the publishing tooling, and evicts the previous release
+// times of the same pages from the cache, so that the new ones are used straight away. The release times are stored
+// before they are evicted, so that the cache cannot be refilled with the previous ones. If any of them cannot be
+// evicted, the rest still are and the response is a 207 Multi-Status listing the paths that were not.
+func ReleaseTimesHandler(store *response.ReleaseTimeStore, cache releaseTimeEvicter) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		w.Header().Set("Cache-Control", "no-store")
+
+		var entries []response.ReleaseTimeEntry
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxReleaseTimesBodySize)).Decode(&entries); err != nil {
+			http.Error(w, "invalid release times: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for i, entry := range entries {
+			if err := validateReleaseTimeEntry(entry); err != nil {
+				http.Error(w, fmt.Sprintf("invalid release time %d: %s", i, err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		added, updated := store.Upsert(entries)
+		releaseTimesResponse := ReleaseTimesResponse{Added: added, Updated: updated}
+
+		for _, entry := range entries {
+			if err := cache.Evict(ctx, entry.Path); err != nil {
+				log.Error(ctx, "error evicting a stored release time from the cache", err, log.Data{"path": entry.Path})
+				releaseTimesResponse.NotEvicted = append(releaseTimesResponse.NotEvicted, entry.Path)
+			}
+		}
+
+		log.Info(ctx, "stored release times", log.Data{"added": added, "updated": updated, "not_evicted": releaseTimesResponse.NotEvicted})
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(releaseTimesResponse.NotEvicted) > 0 {
+			w.WriteHeader(http.StatusMultiStatus)
+		}
+		if err := json.NewEncoder(w).Encode(releaseTimesResponse); err != nil {
+			log.Error(ctx, "error writing the release times response", err)
+		}
+	}
+}
+
+func validateReleaseTimeEntry(entry response.ReleaseTimeEntry) error {
+	if !strings.HasPrefix(entry.Path, "/") {
+		return fmt.Errorf("path %q must start with a slash", entry.Path)
+	}
+
+	if len(entry.Path) > 1 && strings.HasSuffix(entry.Path, "/") {
+		return fmt.Errorf("path %q must not end with a slash", entry.Path)
+	}
+
+	if strings.ContainsAny(entry.Path, "?#") {
+		return fmt.Errorf("path %q must not have a query or fragment", entry.Path)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testAuthToken = "some-token"
+
+type stubEvicter struct {
+	evicted   []string
+	errByPath map[string]error
+}
+
+func (e *stubEvicter) Evict(_ context.Context, uri string) error {
+	e.evicted = append(e.evicted, uri)
+	return e.errByPath[uri]
+}
+
+func (e *stubEvicter) Stats() response.ReleaseTimeCacheStats {
+	return response.ReleaseTimeCacheStats{Entries: 2, Hits: 5, StaleHits: 1, Misses: 2}
+}
+
+func TestReleaseTimesHandler(t *testing.T) {
+	Convey("Given the admin endpoints", t, func() {
+		ctx := context.Background()
+		cfg := &config.Config{AdminAuthToken: testAuthToken, ReleaseTimeStoreTTL: time.Hour}
+		store := response.NewReleaseTimeStore(cfg)
+		cache := &stubEvicter{}
+		clock, err := response.NewClock(cfg)
+		So(err, ShouldBeNil)
+		router := mux.NewRouter()
+		Setup(ctx, router, cfg, store, cache, clock)
+
+		postReleaseTimes := func(authorization, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, ReleaseTimesPath, strings.NewReader(body))
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		Convey("When a batch of release times is sent with the auth token", func() {
+			w := postReleaseTimes("Bearer "+testAuthToken, `[
+				{"path": "/economy/some-page", "release_time": "2024-02-15T07:00:00Z"},
+				{"path": "/economy/other-page", "release_time": null},
+				{"path": "/economy/some-page", "release_time": "2024-02-16T07:00:00Z"}
+			]`)
+
+			Convey("Then the counts of added and updated pages are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
+				So(w.Header().Get("Cache-Control"), ShouldEqual, "no-store")
+				So(w.Body.String(), ShouldEqual, "{\"added\":2,\"updated\":1}\n")
+			})
+
+			Convey("Then the release times are stored and evicted from the cache", func() {
+				releaseTime, statusCode, err := store.GetReleaseTime(ctx, "/economy/some-page")
+				So(err, ShouldBeNil)
+				So(statusCode, ShouldEqual, http.StatusOK)
+				So(releaseTime, ShouldEqual, time.Date(2024, time.February, 16, 7, 0, 0, 0, time.UTC))
+				So(cache.evicted, ShouldResemble, []string{"/economy/some-page", "/economy/other-page", "/economy/some-page"})
+			})
+		})
+
+		Convey("When release times are sent without the right auth token", func() {
+			for _, authorization := range []string{"", testAuthToken, "Bearer wrong-token", "Basic " + testAuthToken} {
+				w := postReleaseTimes(authorization, `[{"path": "/economy/some-page", "release_time": null}]`)
+
+				So(w.Code, ShouldEqual, http.StatusUnauthorized)
+				So(w.Header().Get("WWW-Authenticate"), ShouldEqual, "Bearer")
+			}
+
+			Convey("Then nothing is stored", func() {
+				_, statusCode, _ := store.GetReleaseTime(ctx, "/economy/some-page")
+				So(statusCode, ShouldEqual, http.StatusNotFound)
+				So(cache.evicted, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the release times are not valid", func() {
+			for body, expectedError := range map[string]string{
+				`{"path": "/economy/some-page"}`:                           "invalid release times:",
+				`[{"path": "/economy/some-page", "release_time": "soon"}]`: "invalid release times:",
+				`[{"path": "economy/some-page"}]`:                          `invalid release time 0: path "economy/some-page" must start with a slash`,
+				`[{"path": "/economy/some-page/"}]`:                        `invalid release time 0: path "/economy/some-page/" must not end with a slash`,
+				`[{"path": "/a"}, {"path": "/economy?page=1"}]`:            `invalid release time 1: path "/economy?page=1" must not have a query or fragment`,
+			} {
+				w := postReleaseTimes("Bearer "+testAuthToken, body)
+
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(w.Body.String(), ShouldStartWith, expectedError)
+			}
+
+			Convey("Then nothing is stored", func() {
+				_, statusCode, _ := store.GetReleaseTime(ctx, "/a")
+				So(statusCode, ShouldEqual, http.StatusNotFound)
+				So(cache.evicted, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a release time cannot be evicted from the cache", func() {
+			cache.errByPath = map[string]error{"/economy/some-page": errors.New("evict error")}
+			w := postReleaseTimes("Bearer "+testAuthToken, `[
+				{"path": "/economy/some-page", "release_time": null},
+				{"path": "/economy/other-page", "release_time": null}
+			]`)
+
+			Convey("Then the counts are returned with the path that was not evicted", func() {
+				So(w.Code, ShouldEqual, http.StatusMultiStatus)
+				So(w.Body.String(), ShouldEqual, "{\"added\":2,\"updated\":0,\"not_evicted\":[\"/economy/some-page\"]}\n")
+			})
+
+			Convey("Then every release time is stored and the others are still evicted", func() {
+				_, statusCode, _ := store.GetReleaseTime(ctx, "/economy/some-page")
+				So(statusCode, ShouldEqual, http.StatusOK)
+				So(cache.evicted, ShouldResemble, []string{"/economy/some-page", "/economy/other-page"})
+			})
+		})
+	})
+}
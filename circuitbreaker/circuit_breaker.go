@@ -0,0 +1,188 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// State is the state of a circuit breaker
+type State string
+
+// Possible states of a circuit breaker
+const (
+	Closed   State = "closed"
+	Open     State = "open"
+	HalfOpen State = "half-open"
+)
+
+// CircuitBreaker stops requests being sent to a dependency (e.g. an upstream service) that is failing. While closed, it
+// counts the failed and slow requests over a fixed window (which starts again once it has passed) and opens once too
+// many of them fail. While open, requests fail fast until the open duration has passed, at which point a single probe
+// request is let through (half-open) to decide whether the circuit should close again.
+type CircuitBreaker struct {
+	mutex          sync.Mutex
+	name           string
+	cfg            config.CircuitBreaker
+	now            func() time.Time
+	state          State
+	lastTransition time.Time
+	windowStart    time.Time
+	requests       int
+	failures       int
+	probeInFlight  bool
+}
+
+// New creates a closed circuit breaker for the dependency with the given name
+func New(name string, cfg config.CircuitBreaker) *CircuitBreaker {
+	now := time.Now()
+
+	return &CircuitBreaker{
+		name:           name,
+		cfg:            cfg,
+		now:            time.Now,
+		state:          Closed,
+		lastTransition: now,
+		windowStart:    now,
+	}
+}
+
+// Name returns the name of the dependency that the circuit breaker protects
+func (cb *CircuitBreaker) Name() string {
+	return cb.name
+}
+
+// State returns the current state of the circuit
+func (cb *CircuitBreaker) State() State {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.state
+}
+
+// Allow determines whether a request can be sent to the dependency. Every allowed request must be followed by a call to
+// Record with its outcome, or to Release.
+func (cb *CircuitBreaker) Allow(ctx context.Context) bool {
+	if !cb.cfg.Enabled {
+		return true
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case Open:
+		if cb.now().Sub(cb.lastTransition) < cb.cfg.OpenDuration {
+			return false
+		}
+		cb.transition(ctx, HalfOpen)
+		cb.probeInFlight = true
+		return true
+	case HalfOpen:
+		if cb.probeInFlight {
+			return false
+		}
+		cb.probeInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Record registers the outcome of a request that was allowed through. A request is a failure if it errored or if it took
+// longer than the slow request threshold.
+func (cb *CircuitBreaker) Record(ctx context.Context, failed bool, duration time.Duration) {
+	if !cb.cfg.Enabled {
+		return
+	}
+
+	if cb.cfg.SlowRequestThreshold > 0 && duration >= cb.cfg.SlowRequestThreshold {
+		failed = true
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case HalfOpen:
+		cb.probeInFlight = false
+		if failed {
+			cb.transition(ctx, Open)
+		} else {
+			cb.transition(ctx, Closed)
+		}
+	case Closed:
+		now := cb.now()
+		if now.Sub(cb.windowStart) >= cb.cfg.Window {
+			cb.resetWindow(now)
+		}
+
+		cb.requests++
+		if failed {
+			cb.failures++
+		}
+
+		if cb.requests >= cb.cfg.MinRequests && float64(cb.failures)/float64(cb.requests) >= cb.cfg.FailureRatio {
+			cb.transition(ctx, Open)
+		}
+	}
+}
+
+// Release lets go of a request that was allowed through without registering an outcome, because the request says
+// nothing about the dependency (e.g. it was never sent, or the client went away before it completed). If the request was
+// the probe of a half-open circuit, another probe can be let through.
+func (cb *CircuitBreaker) Release() {
+	if !cb.cfg.Enabled {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.state == HalfOpen {
+		cb.probeInFlight = false
+	}
+}
+
+// transition must be called with the mutex locked
+func (cb *CircuitBreaker) transition(ctx context.Context, state State) {
+	log.Warn(ctx, "circuit breaker state changed", log.Data{
+		"circuit":  cb.name,
+		"from":     cb.state,
+		"to":       state,
+		"requests": cb.requests,
+		"failures": cb.failures,
+	})
+
+	now := cb.now()
+	cb.state = state
+	cb.lastTransition = now
+	cb.resetWindow(now)
+}
+
+func (cb *CircuitBreaker) resetWindow(now time.Time) {
+	cb.windowStart = now
+	cb.requests = 0
+	cb.failures = 0
+}
+
+// Checker reports the state of the circuit to the health check. An open or half-open circuit is reported as a warning,
+// as the proxy is still able to respond without the dependency.
+func (cb *CircuitBreaker) Checker(_ context.Context, state *healthcheck.CheckState) error {
+	cb.mutex.Lock()
+	circuitState, lastTransition := cb.state, cb.lastTransition
+	cb.mutex.Unlock()
+
+	message := fmt.Sprintf("circuit for %s is %s since %s", cb.name, circuitState, lastTransition.UTC().Format(time.RFC3339))
+
+	if circuitState == Closed {
+		return state.Update(healthcheck.StatusOK, message, 0)
+	}
+
+	return state.Update(healthcheck.StatusWarning, message, 0)
+}
@@ -0,0 +1,187 @@
+package circuitbreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var testCircuitBreakerConfig = config.CircuitBreaker{
+	Enabled:              true,
+	FailureRatio:         0.5,
+	SlowRequestThreshold: time.Second,
+	MinRequests:          4,
+	Window:               time.Minute,
+	OpenDuration:         10 * time.Second,
+}
+
+func newTestCircuitBreaker(cfg config.CircuitBreaker) (*CircuitBreaker, *time.Time) {
+	now := time.Date(2024, time.January, 1, 9, 30, 0, 0, time.UTC)
+	circuitBreaker := New("dataset-controller", cfg)
+	circuitBreaker.now = func() time.Time { return now }
+	circuitBreaker.lastTransition, circuitBreaker.windowStart = now, now
+
+	return circuitBreaker, &now
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a closed circuit breaker", t, func() {
+		circuitBreaker, now := newTestCircuitBreaker(testCircuitBreakerConfig)
+		So(circuitBreaker.State(), ShouldEqual, Closed)
+
+		Convey("When fewer requests than the minimum have failed", func() {
+			for i := 0; i < 3; i++ {
+				So(circuitBreaker.Allow(ctx), ShouldBeTrue)
+				circuitBreaker.Record(ctx, true, 0)
+			}
+
+			Convey("Then the circuit stays closed", func() {
+				So(circuitBreaker.State(), ShouldEqual, Closed)
+				So(circuitBreaker.Allow(ctx), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the failure ratio is below the threshold", func() {
+			circuitBreaker.Record(ctx, true, 0)
+			circuitBreaker.Record(ctx, false, 0)
+			circuitBreaker.Record(ctx, false, 0)
+			circuitBreaker.Record(ctx, false, 0)
+
+			Convey("Then the circuit stays closed", func() {
+				So(circuitBreaker.State(), ShouldEqual, Closed)
+			})
+		})
+
+		Convey("When the failures are spread over more than one window", func() {
+			circuitBreaker.Record(ctx, true, 0)
+			circuitBreaker.Record(ctx, true, 0)
+			*now = now.Add(time.Minute)
+			circuitBreaker.Record(ctx, true, 0)
+			circuitBreaker.Record(ctx, true, 0)
+
+			Convey("Then the circuit stays closed", func() {
+				So(circuitBreaker.State(), ShouldEqual, Closed)
+			})
+		})
+
+		Convey("When requests are released rather than recorded", func() {
+			for i := 0; i < 4; i++ {
+				So(circuitBreaker.Allow(ctx), ShouldBeTrue)
+				circuitBreaker.Release()
+			}
+
+			Convey("Then the circuit stays closed", func() {
+				So(circuitBreaker.State(), ShouldEqual, Closed)
+			})
+		})
+
+		Convey("When enough requests are slower than the threshold", func() {
+			for i := 0; i < 4; i++ {
+				circuitBreaker.Record(ctx, false, 2*time.Second)
+			}
+
+			Convey("Then the circuit opens", func() {
+				So(circuitBreaker.State(), ShouldEqual, Open)
+			})
+		})
+
+		Convey("When enough requests fail", func() {
+			circuitBreaker.Record(ctx, true, 0)
+			circuitBreaker.Record(ctx, false, 0)
+			circuitBreaker.Record(ctx, true, 0)
+			circuitBreaker.Record(ctx, false, 0)
+
+			Convey("Then the circuit opens and requests are not allowed", func() {
+				So(circuitBreaker.State(), ShouldEqual, Open)
+				So(circuitBreaker.Allow(ctx), ShouldBeFalse)
+			})
+
+			Convey("And the open duration has passed", func() {
+				*now = now.Add(10 * time.Second)
+
+				Convey("Then a single probe request is allowed through", func() {
+					So(circuitBreaker.Allow(ctx), ShouldBeTrue)
+					So(circuitBreaker.State(), ShouldEqual, HalfOpen)
+					So(circuitBreaker.Allow(ctx), ShouldBeFalse)
+				})
+
+				Convey("Then the circuit closes if the probe request succeeds", func() {
+					So(circuitBreaker.Allow(ctx), ShouldBeTrue)
+					circuitBreaker.Record(ctx, false, 0)
+					So(circuitBreaker.State(), ShouldEqual, Closed)
+					So(circuitBreaker.Allow(ctx), ShouldBeTrue)
+				})
+
+				Convey("Then the circuit opens again if the probe request fails", func() {
+					So(circuitBreaker.Allow(ctx), ShouldBeTrue)
+					circuitBreaker.Record(ctx, true, 0)
+					So(circuitBreaker.State(), ShouldEqual, Open)
+					So(circuitBreaker.Allow(ctx), ShouldBeFalse)
+				})
+
+				Convey("Then another probe request is allowed through if the probe request is released", func() {
+					So(circuitBreaker.Allow(ctx), ShouldBeTrue)
+					circuitBreaker.Release()
+					So(circuitBreaker.State(), ShouldEqual, HalfOpen)
+					So(circuitBreaker.Allow(ctx), ShouldBeTrue)
+				})
+			})
+		})
+	})
+
+	Convey("Given a disabled circuit breaker", t, func() {
+		cfg := testCircuitBreakerConfig
+		cfg.Enabled = false
+		circuitBreaker, _ := newTestCircuitBreaker(cfg)
+
+		Convey("When every request fails", func() {
+			for i := 0; i < 10; i++ {
+				circuitBreaker.Record(ctx, true, 0)
+			}
+
+			Convey("Then requests are still allowed", func() {
+				So(circuitBreaker.State(), ShouldEqual, Closed)
+				So(circuitBreaker.Allow(ctx), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestCircuitBreakerChecker(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a circuit breaker", t, func() {
+		circuitBreaker, _ := newTestCircuitBreaker(testCircuitBreakerConfig)
+
+		Convey("When the circuit is closed", func() {
+			state := healthcheck.NewCheckState("test")
+			err := circuitBreaker.Checker(ctx, state)
+
+			Convey("Then the check reports it as OK", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, healthcheck.StatusOK)
+				So(state.Message(), ShouldEqual, "circuit for dataset-controller is closed since 2024-01-01T09:30:00Z")
+			})
+		})
+
+		Convey("When the circuit is open", func() {
+			for i := 0; i < 4; i++ {
+				circuitBreaker.Record(ctx, true, 0)
+			}
+			state := healthcheck.NewCheckState("test")
+			err := circuitBreaker.Checker(ctx, state)
+
+			Convey("Then the check reports it as a warning", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, healthcheck.StatusWarning)
+				So(state.Message(), ShouldEqual, "circuit for dataset-controller is open since 2024-01-01T09:30:00Z")
+			})
+		})
+	})
+}
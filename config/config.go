@@ -1,6 +1,9 @@
 package config
 
 import (
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -8,30 +11,156 @@ import (
 
 // Config represents service configuration for dp-legacy-cache-proxy
 type Config struct {
-	BindAddr                    string        `envconfig:"BIND_ADDR"`
-	GracefulShutdownTimeout     time.Duration `envconfig:"GRACEFUL_SHUTDOWN_TIMEOUT"`
-	HealthCheckInterval         time.Duration `envconfig:"HEALTHCHECK_INTERVAL"`
-	HealthCheckCriticalTimeout  time.Duration `envconfig:"HEALTHCHECK_CRITICAL_TIMEOUT"`
-	OTBatchTimeout              time.Duration `encconfig:"OTEL_BATCH_TIMEOUT"`
-	OTExporterOTLPEndpoint      string        `envconfig:"OTEL_EXPORTER_OTLP_ENDPOINT"`
-	OTServiceName               string        `envconfig:"OTEL_SERVICE_NAME"`
-	BabbageURL                  string        `envconfig:"BABBAGE_URL"`
-	RelCalURL                   string        `envconfig:"RELEASE_CALENDAR_URL"`
-	EnableSearchController      bool          `envconfig:"ENABLE_SEARCH_CONTROLLER"`
-	SearchControllerURL         string        `envconfig:"SEARCH_CONTROLLER_URL"`
-	DatasetControllerURL        string        `envconfig:"DATASET_CONTROLLER_URL"`
-	LegacyCacheAPIURL           string        `envconfig:"LEGACY_CACHE_API_URL"`
-	CacheTimeDefault            time.Duration `envconfig:"CACHE_TIME_DEFAULT"`
-	CacheTimeErrored            time.Duration `envconfig:"CACHE_TIME_ERRORED"`
-	CacheTimeLong               time.Duration `envconfig:"CACHE_TIME_LONG"`
-	CacheTimeShort              time.Duration `envconfig:"CACHE_TIME_SHORT"`
-	EnablePublishExpiryOffset   bool          `envconfig:"ENABLE_PUBLISH_EXPIRY_OFFSET"`
-	PublishExpiryOffset         time.Duration `envconfig:"PUBLISH_EXPIRY_OFFSET"`
-	ReadTimeout                 time.Duration `envconfig:"READ_TIMEOUT"`
-	WriteTimeout                time.Duration `envconfig:"WRITE_TIMEOUT"`
-	StaleWhileRevalidateSeconds int64         `envconfig:"STALE_WHILE_REVALIDATE_SECONDS"`
-	EnableMaxAgeCountdown       bool          `envconfig:"ENABLE_MAX_AGE_COUNTDOWN"`
-	OtelEnabled                 bool          `envconfig:"OTEL_ENABLED"`
+	BindAddr                     string                 `envconfig:"BIND_ADDR"`
+	GracefulShutdownTimeout      time.Duration          `envconfig:"GRACEFUL_SHUTDOWN_TIMEOUT"`
+	HealthCheckInterval          time.Duration          `envconfig:"HEALTHCHECK_INTERVAL"`
+	HealthCheckCriticalTimeout   time.Duration          `envconfig:"HEALTHCHECK_CRITICAL_TIMEOUT"`
+	OTBatchTimeout               time.Duration          `encconfig:"OTEL_BATCH_TIMEOUT"`
+	OTExporterOTLPEndpoint       string                 `envconfig:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTServiceName                string                 `envconfig:"OTEL_SERVICE_NAME"`
+	BabbageURL                   string                 `envconfig:"BABBAGE_URL"`
+	RelCalURL                    string                 `envconfig:"RELEASE_CALENDAR_URL"`
+	EnableSearchController       bool                   `envconfig:"ENABLE_SEARCH_CONTROLLER"`
+	SearchControllerURL          string                 `envconfig:"SEARCH_CONTROLLER_URL"`
+	DatasetControllerURL         string                 `envconfig:"DATASET_CONTROLLER_URL"`
+	LegacyCacheAPITimeout        time.Duration          `envconfig:"LEGACY_CACHE_API_TIMEOUT"`
+	LegacyCacheAPIURL            string                 `envconfig:"LEGACY_CACHE_API_URL"`
+	CacheTimeDefault             time.Duration          `envconfig:"CACHE_TIME_DEFAULT"`
+	CacheTimeErrored             time.Duration          `envconfig:"CACHE_TIME_ERRORED"`
+	CacheTimeLong                time.Duration          `envconfig:"CACHE_TIME_LONG"`
+	CacheTimeShort               time.Duration          `envconfig:"CACHE_TIME_SHORT"`
+	CacheTimeNotFound            time.Duration          `envconfig:"CACHE_TIME_NOT_FOUND"`
+	CacheTimePermanentRedirect   time.Duration          `envconfig:"CACHE_TIME_PERMANENT_REDIRECT"`
+	CacheTimeTemporaryRedirect   time.Duration          `envconfig:"CACHE_TIME_TEMPORARY_REDIRECT"`
+	CacheTimeJitterPercent       float64                `envconfig:"CACHE_TIME_JITTER_PERCENT"`
+	EnableErrorResponseCaching   bool                   `envconfig:"ENABLE_ERROR_RESPONSE_CACHING"`
+	EnablePublishExpiryOffset    bool                   `envconfig:"ENABLE_PUBLISH_EXPIRY_OFFSET"`
+	PublishExpiryOffset          time.Duration          `envconfig:"PUBLISH_EXPIRY_OFFSET"`
+	ReleaseEmbargoWindow         time.Duration          `envconfig:"RELEASE_EMBARGO_WINDOW"`
+	Environment                  string                 `envconfig:"ENVIRONMENT"`
+	EnableTimeTravel             bool                   `envconfig:"ENABLE_TIME_TRAVEL"`
+	ReadTimeout                  time.Duration          `envconfig:"READ_TIMEOUT"`
+	WriteTimeout                 time.Duration          `envconfig:"WRITE_TIMEOUT"`
+	StaleWhileRevalidateSeconds  int64                  `envconfig:"STALE_WHILE_REVALIDATE_SECONDS"`
+	StaleIfErrorSeconds          int64                  `envconfig:"STALE_IF_ERROR_SECONDS"`
+	EnableMaxAgeCountdown        bool                   `envconfig:"ENABLE_MAX_AGE_COUNTDOWN"`
+	CDNCacheHeader               string                 `envconfig:"CDN_CACHE_HEADER"`
+	CacheTimeBrowser             time.Duration          `envconfig:"CACHE_TIME_BROWSER"`
+	SurrogateKeyHeader           string                 `envconfig:"SURROGATE_KEY_HEADER"`
+	UpstreamCacheDirectives      []string               `envconfig:"UPSTREAM_CACHE_DIRECTIVES"`
+	OtelEnabled                  bool                   `envconfig:"OTEL_ENABLED"`
+	ReleaseTimeSources           []string               `envconfig:"RELEASE_TIME_SOURCES"`
+	ReleaseTimeFile              string                 `envconfig:"RELEASE_TIME_FILE"`
+	ReleaseSchedule              ReleaseSchedule        `envconfig:"RELEASE_SCHEDULE"`
+	ReleaseTimeStoreTTL          time.Duration          `envconfig:"RELEASE_TIME_STORE_TTL"`
+	AdminAuthToken               string                 `envconfig:"ADMIN_AUTH_TOKEN"                  json:"-"`
+	RoutingTableFile             string                 `envconfig:"ROUTING_TABLE_FILE"`
+	CachePolicyFile              string                 `envconfig:"CACHE_POLICY_FILE"`
+	TrustForwardedHeaders        bool                   `envconfig:"TRUST_FORWARDED_HEADERS"`
+	UpstreamMaxAttempts          int                    `envconfig:"UPSTREAM_MAX_ATTEMPTS"`
+	UpstreamAttemptTimeout       time.Duration          `envconfig:"UPSTREAM_ATTEMPT_TIMEOUT"`
+	UpstreamRetryBackoff         time.Duration          `envconfig:"UPSTREAM_RETRY_BACKOFF"`
+	UpstreamRetryBudgetRatio     float64                `envconfig:"UPSTREAM_RETRY_BUDGET_RATIO"`
+	ReleaseTimeCacheMaxEntries   int                    `envconfig:"RELEASE_TIME_CACHE_MAX_ENTRIES"`
+	ReleaseTimeCacheTTL          time.Duration          `envconfig:"RELEASE_TIME_CACHE_TTL"`
+	ReleaseTimeCacheNegativeTTL  time.Duration          `envconfig:"RELEASE_TIME_CACHE_NEGATIVE_TTL"`
+	CircuitBreaker               UpstreamCircuitBreaker `envconfig:"CIRCUIT_BREAKER"`
+	LegacyCacheAPICircuitBreaker CircuitBreaker         `envconfig:"LEGACY_CACHE_API_CIRCUIT_BREAKER"`
+	LegacyCacheAPIDegradedMaxAge time.Duration          `envconfig:"LEGACY_CACHE_API_DEGRADED_MAX_AGE"`
+	BabbageTransport             UpstreamTransport      `envconfig:"BABBAGE"`
+	RelCalTransport              UpstreamTransport      `envconfig:"RELEASE_CALENDAR"`
+	SearchControllerTransport    UpstreamTransport      `envconfig:"SEARCH_CONTROLLER"`
+	DatasetControllerTransport   UpstreamTransport      `envconfig:"DATASET_CONTROLLER"`
+	BabbageHealthCheck           UpstreamHealthCheck    `envconfig:"BABBAGE_HEALTHCHECK"`
+	RelCalHealthCheck            UpstreamHealthCheck    `envconfig:"RELEASE_CALENDAR_HEALTHCHECK"`
+	SearchControllerHealthCheck  UpstreamHealthCheck    `envconfig:"SEARCH_CONTROLLER_HEALTHCHECK"`
+	DatasetControllerHealthCheck UpstreamHealthCheck    `envconfig:"DATASET_CONTROLLER_HEALTHCHECK"`
+	LegacyCacheAPIHealthCheck    UpstreamHealthCheck    `envconfig:"LEGACY_CACHE_API_HEALTHCHECK"`
+	Kafka                        Kafka                  `envconfig:"KAFKA"`
+}
+
+// UpstreamTransport represents the HTTP transport configuration used to connect to a single upstream service. Its
+// environment variables are prefixed with the name of the upstream (e.g. BABBAGE_DIAL_TIMEOUT). Timeout also covers
+// reading the response body, so it is not set by default, in order not to cut off large downloads: a hung upstream is
+// bounded by ResponseHeaderTimeout instead, and the request is cancelled if the client goes away.
+type UpstreamTransport struct {
+	DialTimeout           time.Duration `envconfig:"DIAL_TIMEOUT"`
+	TLSHandshakeTimeout   time.Duration `envconfig:"TLS_HANDSHAKE_TIMEOUT"`
+	ResponseHeaderTimeout time.Duration `envconfig:"RESPONSE_HEADER_TIMEOUT"`
+	Timeout               time.Duration `envconfig:"TIMEOUT"`
+	MaxIdleConns          int           `envconfig:"MAX_IDLE_CONNS"`
+	MaxIdleConnsPerHost   int           `envconfig:"MAX_IDLE_CONNS_PER_HOST"`
+	IdleConnTimeout       time.Duration `envconfig:"IDLE_CONN_TIMEOUT"`
+}
+
+// UpstreamHealthCheck represents the configuration of the health check of a single upstream service. Its environment
+// variables are prefixed with the name of the upstream (e.g. BABBAGE_HEALTHCHECK_PROBE_PATH).
+type UpstreamHealthCheck struct {
+	ProbePath string        `envconfig:"PROBE_PATH"`
+	Timeout   time.Duration `envconfig:"TIMEOUT"`
+	Critical  bool          `envconfig:"CRITICAL"`
+}
+
+// ReleaseSchedule represents the configuration of the release schedule, which is loaded in the background from the
+// Legacy Cache API (or any other service implementing the same endpoint)
+type ReleaseSchedule struct {
+	Enabled      bool          `envconfig:"ENABLED"`
+	URL          string        `envconfig:"URL"`
+	PollInterval time.Duration `envconfig:"POLL_INTERVAL"`
+	LookAhead    time.Duration `envconfig:"LOOK_AHEAD"`
+	LookBehind   time.Duration `envconfig:"LOOK_BEHIND"`
+	StaleAfter   time.Duration `envconfig:"STALE_AFTER"`
+	PageSize     int           `envconfig:"PAGE_SIZE"`
+}
+
+// KafkaTLSProtocolFlag is the value of KAFKA_SEC_PROTO that enables TLS for the connections to Kafka
+const KafkaTLSProtocolFlag = "TLS"
+
+// Kafka represents the configuration of the Kafka consumer of the content published events
+type Kafka struct {
+	Addr                      []string `envconfig:"ADDR"                         json:"-"`
+	Version                   string   `envconfig:"VERSION"`
+	ConsumerMinBrokersHealthy int      `envconfig:"CONSUMER_MIN_BROKERS_HEALTHY"`
+	OffsetOldest              bool     `envconfig:"OFFSET_OLDEST"`
+	NumWorkers                int      `envconfig:"NUM_WORKERS"`
+	SecProtocol               string   `envconfig:"SEC_PROTO"`
+	SecCACerts                string   `envconfig:"SEC_CA_CERTS"`
+	SecClientKey              string   `envconfig:"SEC_CLIENT_KEY"               json:"-"`
+	SecClientCert             string   `envconfig:"SEC_CLIENT_CERT"`
+	SecSkipVerify             bool     `envconfig:"SEC_SKIP_VERIFY"`
+	ContentPublishedEnabled   bool     `envconfig:"CONTENT_PUBLISHED_ENABLED"`
+	ContentPublishedGroup     string   `envconfig:"CONTENT_PUBLISHED_GROUP"`
+	ContentPublishedTopic     string   `envconfig:"CONTENT_PUBLISHED_TOPIC"`
+}
+
+// CircuitBreaker represents the configuration of a circuit breaker
+type CircuitBreaker struct {
+	Enabled              bool          `envconfig:"ENABLED"`
+	FailureRatio         float64       `envconfig:"FAILURE_RATIO"`
+	SlowRequestThreshold time.Duration `envconfig:"SLOW_REQUEST_THRESHOLD"`
+	MinRequests          int           `envconfig:"MIN_REQUESTS"`
+	Window               time.Duration `envconfig:"WINDOW"`
+	OpenDuration         time.Duration `envconfig:"OPEN_DURATION"`
+}
+
+// UpstreamCircuitBreaker represents the configuration of the circuit breaker used for each upstream service, including
+// what the proxy does while a circuit is open. Babbage's circuit breaker is only enabled if BabbageEnabled is also set,
+// as Babbage serves most of the site and nothing can be served in its place while its circuit is open.
+type UpstreamCircuitBreaker struct {
+	CircuitBreaker
+	OpenStatusCode  int  `envconfig:"OPEN_STATUS_CODE"`
+	BabbageEnabled  bool `envconfig:"BABBAGE_ENABLED"`
+	BabbageFallback bool `envconfig:"BABBAGE_FALLBACK"`
+}
+
+var defaultUpstreamTransport = UpstreamTransport{
+	DialTimeout:           5 * time.Second,
+	TLSHandshakeTimeout:   5 * time.Second,
+	ResponseHeaderTimeout: 20 * time.Second,
+	Timeout:               0,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   100,
+	IdleConnTimeout:       90 * time.Second,
 }
 
 var cfg *Config
@@ -53,6 +182,7 @@ func Get() (*Config, error) {
 		OTServiceName:               "dp-legacy-cache-proxy",
 		BabbageURL:                  "http://localhost:8080",
 		LegacyCacheAPIURL:           "http://localhost:29100",
+		LegacyCacheAPITimeout:       2 * time.Second,
 		RelCalURL:                   "http://localhost:27700",
 		SearchControllerURL:         "http://localhost:25000",
 		DatasetControllerURL:        "http://localhost:20200",
@@ -61,14 +191,152 @@ func Get() (*Config, error) {
 		CacheTimeErrored:            30 * time.Second,
 		CacheTimeLong:               4 * time.Hour,
 		CacheTimeShort:              10 * time.Second,
+		CacheTimeNotFound:           10 * time.Second,
+		CacheTimePermanentRedirect:  4 * time.Hour,
+		CacheTimeTemporaryRedirect:  10 * time.Second,
+		CacheTimeJitterPercent:      0,
+		EnableErrorResponseCaching:  false,
 		EnablePublishExpiryOffset:   false,
 		PublishExpiryOffset:         3 * time.Minute,
+		ReleaseEmbargoWindow:        0,
+		Environment:                 "",
+		EnableTimeTravel:            false,
 		ReadTimeout:                 15 * time.Second,
 		WriteTimeout:                30 * time.Second,
 		StaleWhileRevalidateSeconds: -1,
+		StaleIfErrorSeconds:         -1,
 		EnableMaxAgeCountdown:       true,
+		CDNCacheHeader:              "",
+		CacheTimeBrowser:            time.Minute,
+		SurrogateKeyHeader:          "",
+		UpstreamCacheDirectives:     []string{"no-store", "no-cache", "max-age", "s-maxage"},
 		OtelEnabled:                 false,
+		ReleaseSchedule: ReleaseSchedule{
+			Enabled:      false,
+			URL:          "http://localhost:29100",
+			PollInterval: time.Minute,
+			LookAhead:    24 * time.Hour,
+			LookBehind:   time.Hour,
+			StaleAfter:   5 * time.Minute,
+			PageSize:     500,
+		},
+		ReleaseTimeStoreTTL:         24 * time.Hour,
+		AdminAuthToken:              "",
+		RoutingTableFile:            "",
+		CachePolicyFile:             "",
+		ReleaseTimeSources:          []string{"legacy-cache-api"},
+		ReleaseTimeFile:             "",
+		TrustForwardedHeaders:       true,
+		UpstreamMaxAttempts:         3,
+		UpstreamAttemptTimeout:      0,
+		UpstreamRetryBackoff:        50 * time.Millisecond,
+		UpstreamRetryBudgetRatio:    0.1,
+		ReleaseTimeCacheMaxEntries:  10000,
+		ReleaseTimeCacheTTL:         time.Minute,
+		ReleaseTimeCacheNegativeTTL: 10 * time.Second,
+		CircuitBreaker: UpstreamCircuitBreaker{
+			CircuitBreaker: CircuitBreaker{
+				Enabled:              true,
+				FailureRatio:         0.5,
+				SlowRequestThreshold: 10 * time.Second,
+				MinRequests:          20,
+				Window:               30 * time.Second,
+				OpenDuration:         15 * time.Second,
+			},
+			OpenStatusCode:  http.StatusServiceUnavailable,
+			BabbageEnabled:  false,
+			BabbageFallback: false,
+		},
+		LegacyCacheAPICircuitBreaker: CircuitBreaker{
+			Enabled:              true,
+			FailureRatio:         0.5,
+			SlowRequestThreshold: time.Second,
+			MinRequests:          20,
+			Window:               30 * time.Second,
+			OpenDuration:         15 * time.Second,
+		},
+		LegacyCacheAPIDegradedMaxAge: time.Minute,
+		BabbageTransport:             defaultUpstreamTransport,
+		RelCalTransport:              defaultUpstreamTransport,
+		SearchControllerTransport:    defaultUpstreamTransport,
+		DatasetControllerTransport:   defaultUpstreamTransport,
+		BabbageHealthCheck:           UpstreamHealthCheck{ProbePath: "/health", Timeout: 5 * time.Second, Critical: true},
+		RelCalHealthCheck:            UpstreamHealthCheck{ProbePath: "/health", Timeout: 5 * time.Second, Critical: true},
+		SearchControllerHealthCheck:  UpstreamHealthCheck{ProbePath: "/health", Timeout: 5 * time.Second, Critical: true},
+		DatasetControllerHealthCheck: UpstreamHealthCheck{ProbePath: "/health", Timeout: 5 * time.Second, Critical: true},
+		LegacyCacheAPIHealthCheck:    UpstreamHealthCheck{ProbePath: "/health", Timeout: 5 * time.Second, Critical: false},
+		Kafka: Kafka{
+			Addr:                      []string{"localhost:9092", "localhost:9093", "localhost:9094"},
+			Version:                   "3.5.1",
+			ConsumerMinBrokersHealthy: 1,
+			OffsetOldest:              true,
+			NumWorkers:                1,
+			SecProtocol:               "",
+			SecCACerts:                "",
+			SecClientKey:              "",
+			SecClientCert:             "",
+			SecSkipVerify:             false,
+			ContentPublishedEnabled:   false,
+			ContentPublishedGroup:     "dp-legacy-cache-proxy",
+			ContentPublishedTopic:     "content-updated",
+		},
 	}
 
 	return cfg, envconfig.Process("", cfg)
 }
+
+// cdnCacheHeaders are the headers that CDN_CACHE_HEADER can name
+var cdnCacheHeaders = []string{"Surrogate-Control", "CDN-Cache-Control"}
+
+// surrogateKeyHeaders are the headers that SURROGATE_KEY_HEADER can name
+var surrogateKeyHeaders = []string{"Surrogate-Key", "Cache-Tag"}
+
+// Validate checks the values of the configuration that cannot be used as they are, so that the service can refuse to
+// start rather than fail once it is running
+func (cfg *Config) Validate() error {
+	if cfg.CDNCacheHeader != "" && !ContainsFold(cdnCacheHeaders, cfg.CDNCacheHeader) {
+		return fmt.Errorf("invalid CDN cache header %q, which must be one of %q", cfg.CDNCacheHeader, cdnCacheHeaders)
+	}
+
+	if cfg.SurrogateKeyHeader != "" && !ContainsFold(surrogateKeyHeaders, cfg.SurrogateKeyHeader) {
+		return fmt.Errorf("invalid surrogate key header %q, which must be one of %q", cfg.SurrogateKeyHeader, surrogateKeyHeaders)
+	}
+
+	if cfg.ReleaseSchedule.Enabled {
+		if cfg.ReleaseSchedule.PollInterval <= 0 {
+			return fmt.Errorf("invalid release schedule poll interval %v, which must be greater than 0", cfg.ReleaseSchedule.PollInterval)
+		}
+
+		if cfg.ReleaseSchedule.StaleAfter <= 0 {
+			return fmt.Errorf("invalid release schedule stale after %v, which must be greater than 0", cfg.ReleaseSchedule.StaleAfter)
+		}
+
+		if cfg.ReleaseSchedule.PageSize <= 0 {
+			return fmt.Errorf("invalid release schedule page size %d, which must be greater than 0", cfg.ReleaseSchedule.PageSize)
+		}
+	}
+
+	return nil
+}
+
+// HTTPMethods are the request methods that the routes of the routing table and the rules of the cache policy can match
+var HTTPMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// IsHTTPMethod determines if the given method is one of HTTPMethods, ignoring case
+func IsHTTPMethod(method string) bool {
+	return ContainsFold(HTTPMethods, method)
+}
+
+// ContainsFold determines if the given values contain the given value, ignoring case
+func ContainsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
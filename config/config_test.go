@@ -19,6 +19,16 @@ func TestConfig(t *testing.T) {
 		})
 
 		Convey("When the config values are retrieved", func() {
+			expectedUpstreamTransport := UpstreamTransport{
+				DialTimeout:           5 * time.Second,
+				TLSHandshakeTimeout:   5 * time.Second,
+				ResponseHeaderTimeout: 20 * time.Second,
+				Timeout:               0,
+				MaxIdleConns:          100,
+				MaxIdleConnsPerHost:   100,
+				IdleConnTimeout:       90 * time.Second,
+			}
+
 			Convey("Then there should be no error returned, and values are as expected", func() {
 				configuration, err = Get() // This Get() is only called once, when inside this function
 				So(err, ShouldBeNil)
@@ -33,20 +43,103 @@ func TestConfig(t *testing.T) {
 					BabbageURL:                  "http://localhost:8080",
 					DatasetControllerURL:        "http://localhost:20200",
 					LegacyCacheAPIURL:           "http://localhost:29100",
+					LegacyCacheAPITimeout:       2 * time.Second,
 					RelCalURL:                   "http://localhost:27700",
 					SearchControllerURL:         "http://localhost:25000",
 					CacheTimeDefault:            15 * time.Minute,
 					CacheTimeErrored:            30 * time.Second,
 					CacheTimeLong:               4 * time.Hour,
 					CacheTimeShort:              10 * time.Second,
+					CacheTimeNotFound:           10 * time.Second,
+					CacheTimePermanentRedirect:  4 * time.Hour,
+					CacheTimeTemporaryRedirect:  10 * time.Second,
+					CacheTimeJitterPercent:      0,
+					EnableErrorResponseCaching:  false,
 					EnablePublishExpiryOffset:   false,
 					PublishExpiryOffset:         3 * time.Minute,
+					ReleaseEmbargoWindow:        0,
+					Environment:                 "",
+					EnableTimeTravel:            false,
 					ReadTimeout:                 15 * time.Second,
 					WriteTimeout:                30 * time.Second,
 					StaleWhileRevalidateSeconds: -1,
+					StaleIfErrorSeconds:         -1,
 					EnableMaxAgeCountdown:       true,
+					CDNCacheHeader:              "",
+					CacheTimeBrowser:            time.Minute,
+					SurrogateKeyHeader:          "",
+					UpstreamCacheDirectives:     []string{"no-store", "no-cache", "max-age", "s-maxage"},
 					OtelEnabled:                 false,
 					EnableSearchController:      false,
+					ReleaseSchedule: ReleaseSchedule{
+						Enabled:      false,
+						URL:          "http://localhost:29100",
+						PollInterval: time.Minute,
+						LookAhead:    24 * time.Hour,
+						LookBehind:   time.Hour,
+						StaleAfter:   5 * time.Minute,
+						PageSize:     500,
+					},
+					ReleaseTimeStoreTTL:         24 * time.Hour,
+					AdminAuthToken:              "",
+					RoutingTableFile:            "",
+					CachePolicyFile:             "",
+					ReleaseTimeSources:          []string{"legacy-cache-api"},
+					ReleaseTimeFile:             "",
+					TrustForwardedHeaders:       true,
+					UpstreamMaxAttempts:         3,
+					UpstreamAttemptTimeout:      0,
+					UpstreamRetryBackoff:        50 * time.Millisecond,
+					UpstreamRetryBudgetRatio:    0.1,
+					ReleaseTimeCacheMaxEntries:  10000,
+					ReleaseTimeCacheTTL:         time.Minute,
+					ReleaseTimeCacheNegativeTTL: 10 * time.Second,
+					CircuitBreaker: UpstreamCircuitBreaker{
+						CircuitBreaker: CircuitBreaker{
+							Enabled:              true,
+							FailureRatio:         0.5,
+							SlowRequestThreshold: 10 * time.Second,
+							MinRequests:          20,
+							Window:               30 * time.Second,
+							OpenDuration:         15 * time.Second,
+						},
+						OpenStatusCode:  503,
+						BabbageEnabled:  false,
+						BabbageFallback: false,
+					},
+					LegacyCacheAPICircuitBreaker: CircuitBreaker{
+						Enabled:              true,
+						FailureRatio:         0.5,
+						SlowRequestThreshold: time.Second,
+						MinRequests:          20,
+						Window:               30 * time.Second,
+						OpenDuration:         15 * time.Second,
+					},
+					LegacyCacheAPIDegradedMaxAge: time.Minute,
+					BabbageTransport:             expectedUpstreamTransport,
+					RelCalTransport:              expectedUpstreamTransport,
+					SearchControllerTransport:    expectedUpstreamTransport,
+					DatasetControllerTransport:   expectedUpstreamTransport,
+					BabbageHealthCheck:           UpstreamHealthCheck{ProbePath: "/health", Timeout: 5 * time.Second, Critical: true},
+					RelCalHealthCheck:            UpstreamHealthCheck{ProbePath: "/health", Timeout: 5 * time.Second, Critical: true},
+					SearchControllerHealthCheck:  UpstreamHealthCheck{ProbePath: "/health", Timeout: 5 * time.Second, Critical: true},
+					DatasetControllerHealthCheck: UpstreamHealthCheck{ProbePath: "/health", Timeout: 5 * time.Second, Critical: true},
+					LegacyCacheAPIHealthCheck:    UpstreamHealthCheck{ProbePath: "/health", Timeout: 5 * time.Second, Critical: false},
+					Kafka: Kafka{
+						Addr:                      []string{"localhost:9092", "localhost:9093", "localhost:9094"},
+						Version:                   "3.5.1",
+						ConsumerMinBrokersHealthy: 1,
+						OffsetOldest:              true,
+						NumWorkers:                1,
+						SecProtocol:               "",
+						SecCACerts:                "",
+						SecClientKey:              "",
+						SecClientCert:             "",
+						SecSkipVerify:             false,
+						ContentPublishedEnabled:   false,
+						ContentPublishedGroup:     "dp-legacy-cache-proxy",
+						ContentPublishedTopic:     "content-updated",
+					},
 				})
 			})
 
@@ -59,3 +152,98 @@ func TestConfig(t *testing.T) {
 		})
 	})
 }
+
+func TestValidate(t *testing.T) {
+	Convey("Given a valid configuration", t, func() {
+		cfg := &Config{
+			ReleaseSchedule: ReleaseSchedule{
+				Enabled:      true,
+				PollInterval: time.Minute,
+				StaleAfter:   5 * time.Minute,
+				PageSize:     500,
+			},
+		}
+
+		Convey("Then it is valid", func() {
+			So(cfg.Validate(), ShouldBeNil)
+		})
+
+		Convey("When the CDN cache header is not supported", func() {
+			cfg.CDNCacheHeader = "X-Cache-Control"
+
+			Convey("Then an error is returned", func() {
+				So(cfg.Validate(), ShouldBeError, `invalid CDN cache header "X-Cache-Control", which must be one of ["Surrogate-Control" "CDN-Cache-Control"]`)
+			})
+		})
+
+		Convey("When a supported CDN cache header is set in a different case", func() {
+			cfg.CDNCacheHeader = "cdn-cache-control"
+
+			Convey("Then it is valid", func() {
+				So(cfg.Validate(), ShouldBeNil)
+			})
+		})
+
+		Convey("When the surrogate key header is not supported", func() {
+			cfg.SurrogateKeyHeader = "X-Surrogate-Key"
+
+			Convey("Then an error is returned", func() {
+				So(cfg.Validate(), ShouldBeError, `invalid surrogate key header "X-Surrogate-Key", which must be one of ["Surrogate-Key" "Cache-Tag"]`)
+			})
+		})
+
+		Convey("When a supported surrogate key header is set in a different case", func() {
+			cfg.SurrogateKeyHeader = "cache-tag"
+
+			Convey("Then it is valid", func() {
+				So(cfg.Validate(), ShouldBeNil)
+			})
+		})
+
+		Convey("When the release schedule poll interval is not positive", func() {
+			cfg.ReleaseSchedule.PollInterval = 0
+
+			Convey("Then an error is returned", func() {
+				So(cfg.Validate(), ShouldBeError, "invalid release schedule poll interval 0s, which must be greater than 0")
+			})
+		})
+
+		Convey("When the release schedule stale after duration is not positive", func() {
+			cfg.ReleaseSchedule.StaleAfter = -time.Minute
+
+			Convey("Then an error is returned", func() {
+				So(cfg.Validate(), ShouldBeError, "invalid release schedule stale after -1m0s, which must be greater than 0")
+			})
+		})
+
+		Convey("When the release schedule page size is not positive", func() {
+			cfg.ReleaseSchedule.PageSize = 0
+
+			Convey("Then an error is returned", func() {
+				So(cfg.Validate(), ShouldBeError, "invalid release schedule page size 0, which must be greater than 0")
+			})
+
+			Convey("And the release schedule is disabled", func() {
+				cfg.ReleaseSchedule.Enabled = false
+
+				Convey("Then it is valid", func() {
+					So(cfg.Validate(), ShouldBeNil)
+				})
+			})
+		})
+	})
+}
+
+func TestIsHTTPMethod(t *testing.T) {
+	Convey("Given request methods in any case", t, func() {
+		Convey("Then the standard methods are valid", func() {
+			So(IsHTTPMethod("GET"), ShouldBeTrue)
+			So(IsHTTPMethod("options"), ShouldBeTrue)
+		})
+
+		Convey("Then any other method is not valid", func() {
+			So(IsHTTPMethod("FETCH"), ShouldBeFalse)
+			So(IsHTTPMethod(""), ShouldBeFalse)
+		})
+	})
+}
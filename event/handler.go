@@ -0,0 +1,46 @@
+package event
+
+import (
+	"context"
+
+	kafka "github.com/ONSdigital/dp-kafka/v4"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/schema"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/pkg/errors"
+)
+
+// ReleaseTimeEvicter is anything holding release times that can be out of date once a page has been published
+type ReleaseTimeEvicter interface {
+	Evict(ctx context.Context, uri string) error
+}
+
+// ContentPublishedHandler evicts the release time of every page that is published, so that its new release time is
+// looked up the next time the page is requested, rather than once the cached one expires
+type ContentPublishedHandler struct {
+	ReleaseTimes []ReleaseTimeEvicter
+}
+
+// NewContentPublishedHandler creates a ContentPublishedHandler evicting the release times from all the given evicters
+func NewContentPublishedHandler(releaseTimes ...ReleaseTimeEvicter) *ContentPublishedHandler {
+	return &ContentPublishedHandler{ReleaseTimes: releaseTimes}
+}
+
+// Handle evicts the release time of the page in a content published event. It is registered with the Kafka consumer.
+func (h *ContentPublishedHandler) Handle(ctx context.Context, _ int, msg kafka.Message) error {
+	var contentPublished ContentPublished
+	if err := schema.ContentPublishedEvent.Unmarshal(msg.GetData(), &contentPublished); err != nil {
+		return errors.Wrap(err, "unable to unmarshal the content published event")
+	}
+
+	logData := log.Data{"uri": contentPublished.URI, "collection_id": contentPublished.CollectionID}
+	log.Info(ctx, "evicting the release time of a published page", logData)
+
+	for _, releaseTimes := range h.ReleaseTimes {
+		if err := releaseTimes.Evict(ctx, contentPublished.URI); err != nil {
+			log.Error(ctx, "error evicting the release time of a published page", err, logData)
+			return errors.Wrap(err, "unable to evict the release time")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ONSdigital/dp-kafka/v4/kafkatest"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/event"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/schema"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var errEvict = errors.New("evict error")
+
+type evicterFunc func(ctx context.Context, uri string) error
+
+func (f evicterFunc) Evict(ctx context.Context, uri string) error {
+	return f(ctx, uri)
+}
+
+func TestContentPublishedHandler(t *testing.T) {
+	Convey("Given a content published handler with two release time evicters", t, func() {
+		ctx := context.Background()
+		var cacheEvictions, scheduleEvictions []string
+		var cacheErr error
+		handler := event.NewContentPublishedHandler(
+			evicterFunc(func(_ context.Context, uri string) error {
+				cacheEvictions = append(cacheEvictions, uri)
+				return cacheErr
+			}),
+			evicterFunc(func(_ context.Context, uri string) error {
+				scheduleEvictions = append(scheduleEvictions, uri)
+				return nil
+			}),
+		)
+
+		Convey("When a content published event is handled", func() {
+			data, err := schema.ContentPublishedEvent.Marshal(&event.ContentPublished{URI: "/economy/some-page", CollectionID: "collection"})
+			So(err, ShouldBeNil)
+			msg, err := kafkatest.NewMessage(data, 0)
+			So(err, ShouldBeNil)
+
+			err = handler.Handle(ctx, 1, msg)
+
+			Convey("Then the release time of the page is evicted from both", func() {
+				So(err, ShouldBeNil)
+				So(cacheEvictions, ShouldResemble, []string{"/economy/some-page"})
+				So(scheduleEvictions, ShouldResemble, []string{"/economy/some-page"})
+			})
+		})
+
+		Convey("When the release time cannot be evicted", func() {
+			cacheErr = errEvict
+			data, err := schema.ContentPublishedEvent.Marshal(&event.ContentPublished{URI: "/economy/some-page"})
+			So(err, ShouldBeNil)
+			msg, err := kafkatest.NewMessage(data, 0)
+			So(err, ShouldBeNil)
+
+			err = handler.Handle(ctx, 1, msg)
+
+			Convey("Then the error is returned", func() {
+				So(errors.Cause(err), ShouldEqual, errEvict)
+			})
+		})
+
+		Convey("When the message is not a content published event", func() {
+			msg, err := kafkatest.NewMessage([]byte("not avro"), 0)
+			So(err, ShouldBeNil)
+
+			err = handler.Handle(ctx, 1, msg)
+
+			Convey("Then an error is returned and nothing is evicted", func() {
+				So(err, ShouldNotBeNil)
+				So(cacheEvictions, ShouldBeEmpty)
+				So(scheduleEvictions, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,11 @@
+package event
+
+// ContentPublished is the event sent by the publishing pipeline when a page is published
+type ContentPublished struct {
+	URI          string `avro:"uri"`
+	DataType     string `avro:"data_type"`
+	CollectionID string `avro:"collection_id"`
+	JobID        string `avro:"job_id"`
+	SearchIndex  string `avro:"search_index"`
+	TraceID      string `avro:"trace_id"`
+}
@@ -0,0 +1,51 @@
+Feature: Admin release times
+
+  The publishing tooling can send the release times of pages to the proxy directly, e.g. when a release is rescheduled
+  in an emergency. These release times are used straight away, rather than once the cached ones expire.
+
+  Background:
+    Given config includes ADMIN_AUTH_TOKEN with a value of "some-token"
+    And Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+
+  Scenario: Release times are added and updated
+    When I set the "Authorization" header to "Bearer some-token"
+    And I POST "/admin/release-times"
+      """
+      [
+        {"path": "/economy/some-page", "release_time": "2024-02-15T07:00:00Z"},
+        {"path": "/economy/other-page", "release_time": null},
+        {"path": "/economy/some-page", "release_time": "2024-02-16T07:00:00Z"}
+      ]
+      """
+    Then the HTTP status code should be "200"
+    And the response header "Cache-Control" should be "no-store"
+    And I should receive the following JSON response:
+      """
+      {"added": 2, "updated": 1}
+      """
+
+  Scenario: Release times are rejected without the auth token
+    When I POST "/admin/release-times"
+      """
+      [{"path": "/economy/some-page", "release_time": null}]
+      """
+    Then the HTTP status code should be "401"
+
+  Scenario: Invalid release times are rejected
+    When I set the "Authorization" header to "Bearer some-token"
+    And I POST "/admin/release-times"
+      """
+      [{"path": "economy/some-page", "release_time": null}]
+      """
+    Then the HTTP status code should be "400"
+
+  Scenario: Other requests are still proxied
+    When the Proxy receives a GET request for "/admin/release-times"
+    Then the HTTP status code should be "200"
+    And I should receive the following response:
+      """
+      Mock response from Babbage
+      """
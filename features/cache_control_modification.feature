@@ -33,3 +33,61 @@ Feature: Modification of Cache-Control header to append max-age when Babbage has
     | Babbage-Cache-Control |
     | public                |
     | private               |
+
+  Scenario: Proxy adds stale-if-error alongside stale-while-revalidate
+    Given Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+    And config includes STALE_WHILE_REVALIDATE_SECONDS with a value of "33"
+    And config includes STALE_IF_ERROR_SECONDS with a value of "86400"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=900, max-age=900, stale-while-revalidate=33, stale-if-error=86400"
+
+  Scenario: Proxy does not add stale-if-error during the countdown to a release
+    Given Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+    And the "/some-path" page will have a release in the near future
+    And config includes STALE_IF_ERROR_SECONDS with a value of "86400"
+    When the Proxy receives a GET request for "/some-path"
+    Then the max-age,s-maxage directives should be calculated, rather than predefined
+    And the response header "Cache-Control" should not contain "stale-if-error"
+
+  Scenario: Proxy does not add stale-if-error to a page that was released recently
+    Given Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+    And the "/some-path" page was released recently
+    And config includes STALE_IF_ERROR_SECONDS with a value of "86400"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=10, max-age=10"
+
+  Scenario Outline: Proxy merges its directives into the other directives set by Babbage
+    Given Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+    And Babbage will set the "Cache-Control" header to "<Babbage-Cache-Control>"
+    And config includes STALE_WHILE_REVALIDATE_SECONDS with a value of "33"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "<Cache-Control>"
+
+  Examples:
+    | Babbage-Cache-Control            | Cache-Control                                                                 |
+    | Public                           | public, s-maxage=900, max-age=900, stale-while-revalidate=33                  |
+    | public, must-revalidate          | public, must-revalidate, s-maxage=900, max-age=900, stale-while-revalidate=33 |
+    | no-transform                     | no-transform, s-maxage=900, max-age=900, stale-while-revalidate=33            |
+    | public, stale-while-revalidate=5 | public, stale-while-revalidate=33, s-maxage=900, max-age=900                  |
+
+  Scenario: Proxy overrides the max-age set by Babbage when max-age is not an upstream cache directive
+    Given Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+    And Babbage will set the "Cache-Control" header to "public, max-age=60"
+    And config includes UPSTREAM_CACHE_DIRECTIVES with a value of "no-store,no-cache"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, max-age=900, s-maxage=900"
@@ -0,0 +1,43 @@
+Feature: CDN cache header
+
+  When a CDN cache header is configured, the proxy sets the CDN's max-age in that header rather than as the s-maxage
+  directive, and the max-age of the Cache-Control header, for browsers, is at most the browser cache time.
+
+  Background:
+    Given Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+
+  Scenario Outline: The CDN's max-age is set in the CDN cache header
+    Given config includes CDN_CACHE_HEADER with a value of "<cdn-cache-header>"
+    And the "/some-path" page was released long ago
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "<cdn-cache-header>" should be "max-age=900"
+    And the response header "Cache-Control" should be "public, max-age=60"
+    And the Expires header should match the max-age directive
+  Examples:
+    | cdn-cache-header  |
+    | Surrogate-Control |
+    | CDN-Cache-Control |
+
+  Scenario: The browsers' max-age is not longer than the CDN's
+    Given config includes CDN_CACHE_HEADER with a value of "Surrogate-Control"
+    And config includes CACHE_TIME_BROWSER with a value of "1h"
+    When the Proxy receives a GET request for "/img/national-statistics.png"
+    Then the response header "Surrogate-Control" should be "max-age=14400"
+    And the response header "Cache-Control" should be "public, max-age=3600"
+
+  Scenario: Both max-ages count down to a release
+    Given config includes CDN_CACHE_HEADER with a value of "Surrogate-Control"
+    And the "/some-path" page will have a release in the near future
+    When the Proxy receives a GET request for "/some-path"
+    Then the max-age,s-maxage directives should be calculated, rather than predefined
+    And the Expires header should match the max-age directive
+
+  Scenario: The CDN cache header is not set for private responses
+    Given config includes CDN_CACHE_HEADER with a value of "Surrogate-Control"
+    And Babbage will set the "Cache-Control" header to "private"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "private, max-age=60"
+    And the response should not have a "Surrogate-Control" header
@@ -0,0 +1,19 @@
+Feature: Content published events
+
+  The proxy can consume the content published events sent by the publishing pipeline. The release time of every page
+  that is published is then evicted from the cache, so that its new release time is used straight away.
+
+  Background:
+    Given the Proxy consumes content published events
+    And Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+
+  Scenario: The release time of a published page is evicted from the cache
+    Given the "/economy/some-page" page was released long ago
+    When the Proxy receives a GET request for "/economy/some-page"
+    Then the release time cache should hold 1 entry
+    When a content published event is received for "/economy/some-page"
+    Then the release time cache should hold 0 entries
+
@@ -0,0 +1,39 @@
+Feature: Jittered cache time
+
+  When a cache time jitter percentage is configured, the default and long cache times are spread by up to that
+  percentage either way, depending on the page's path, so that pages cached at the same moment do not all expire
+  together. The max-age is never jittered when it counts down to a release or is within the publish expiry offset.
+
+  Background:
+    Given Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+    And config includes CACHE_TIME_JITTER_PERCENT with a value of "10"
+
+  Scenario: The default cache time is jittered
+    When the Proxy receives a GET request for "/some-path"
+    Then the max-age directive should be between 810 and 990
+    And the s-maxage directive should be between 810 and 990
+    And the Expires header should match the max-age directive
+
+  Scenario: The long cache time is jittered
+    When the Proxy receives a GET request for "/img/national-statistics.png"
+    Then the max-age directive should be between 12960 and 15840
+
+  Scenario: Other cache times are not jittered
+    Given Babbage will send the following response with status "301":
+      """
+      """
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=14400, max-age=14400"
+
+  Scenario: The countdown to a release is not jittered
+    Given the "/some-path" page will have a release in the near future
+    When the Proxy receives a GET request for "/some-path"
+    Then the max-age,s-maxage directives should be calculated, rather than predefined
+
+  Scenario: The publish expiry offset is not jittered
+    Given the "/some-path" page was released recently
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=10, max-age=10"
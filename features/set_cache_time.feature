@@ -1,8 +1,8 @@
 Feature: Set cache time
 
   The proxy may alter the Cache-Control header in the Babbage response in order to set the "max-age" directive to one of
-  four preconfigured values: short, long, errored or default cache time. It may also be set to a calculated value if
-  it is a page that is about to be released.
+  its preconfigured values: short, long, errored, default, not found or (permanent or temporary) redirect cache time. It
+  may also be set to a calculated value if it is a page that is about to be released.
 
   Background:
     Given Babbage will send the following response:
@@ -58,18 +58,64 @@ Feature: Set cache time
     | /some-url                                               |     900 |
     | /favicon.ico                                            |   14400 |
 
-  Scenario Outline: The response from Babbage is 301 so we set Cache-Control header
-    Given Babbage will send the following response with status "301":
+  Scenario Outline: The response from Babbage is a redirect so we set the Cache-Control header for that kind of redirect
+    Given Babbage will send the following response with status "<status-code>":
       """
       """
     And Babbage will set the "X-Some-Header" header to "some-value"
     When the Proxy receives a GET request for "<sample-uri>"
     Then the response header "Cache-Control" should be "public, s-maxage=<max-age>, max-age=<max-age>"
-    And the HTTP status code should be "301"
+    And the HTTP status code should be "<status-code>"
   Examples:
-    | sample-uri                                              | max-age |
-    | /some-url                                               |     900 |
-    | /favicon.ico                                            |   14400 |
+    | sample-uri   | status-code | max-age |
+    | /some-url    | 301         |   14400 |
+    | /favicon.ico | 301         |   14400 |
+    | /some-url    | 308         |   14400 |
+    | /some-url    | 302         |      10 |
+    | /favicon.ico | 302         |      10 |
+    | /some-url    | 307         |      10 |
+
+  Scenario: The response from Babbage is 404 so we set the not found cache time
+    Given Babbage will send the following response with status "404":
+      """
+      Not found
+      """
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=10, max-age=10"
+    And the HTTP status code should be "404"
+
+  Scenario: The response from Babbage is 404 for a page that will be released in the distant future
+    Given Babbage will send the following response with status "404":
+      """
+      Not found
+      """
+    And the "/some-path" page will have a release in the distant future
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=10, max-age=10"
+
+  Scenario: The response from Babbage is 404 for a page that will be released before the not found cache time expires
+    Given Babbage will send the following response with status "404":
+      """
+      Not found
+      """
+    And the "/some-path" page will have a release in the near future
+    When the Proxy receives a GET request for "/some-path"
+    Then the max-age,s-maxage directives should be calculated, rather than predefined
+
+  Scenario Outline: The response from Babbage is an error and error response caching is enabled
+    Given Babbage will send the following response with status "<status-code>":
+      """
+      Error
+      """
+    And config includes ENABLE_ERROR_RESPONSE_CACHING with a value of "true"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=30, max-age=30"
+    And the HTTP status code should be "<status-code>"
+  Examples:
+    | status-code |
+    | 410         |
+    | 500         |
+    | 503         |
 
   Scenario: Return the errored cache time when the Legacy Cache API returns an error
     Given the Legacy Cache API has an error
@@ -182,3 +228,54 @@ Feature: Set cache time
     | /some-path   | /some-path                  |
     | economy/economicoutputandproductivity/productivitymeasures/articles/gdpandthelabourmarket/october2014 | economy/economicoutputandproductivity/productivitymeasures/articles/gdpandthelabourmarket/october2014/relateddata |
     | economy/economicoutputandproductivity/productivitymeasures/articles/gdpandthelabourmarket/latest      | economy/economicoutputandproductivity/productivitymeasures/articles/gdpandthelabourmarket/previousreleases        |
+
+  Scenario: The Expires header matches the max-age and the upstream's Age header is removed
+    Given Babbage will set the "Age" header to "300"
+    And the "/some-path" page was released long ago
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=900, max-age=900"
+    And the Expires header should match the max-age directive
+    And the response should not have an "Age" header
+
+  Scenario: The Expires header is the release time when the max-age counts down to a release
+    Given the "/some-path" page will have a release in the near future
+    When the Proxy receives a GET request for "/some-path"
+    Then the max-age,s-maxage directives should be calculated, rather than predefined
+    And the Expires header should match the max-age directive
+
+  Scenario: The upstream's Age header is kept when the response is not modified
+    Given Babbage will send the following response with status "500":
+      """
+      Error
+      """
+    And Babbage will set the "Age" header to "300"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Age" should be "300"
+    And the response should not have an "Expires" header
+
+  Scenario: The response is not stored when the release time is within the release embargo window
+    Given the "/some-path" page will have a release in the near future
+    And config includes RELEASE_EMBARGO_WINDOW with a value of "30s"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "no-store"
+    And the response should not have an "Age" header
+
+  Scenario: The response is not stored by the CDN when the release time is within the release embargo window
+    Given the "/some-path" page will have a release in the near future
+    And config includes RELEASE_EMBARGO_WINDOW with a value of "30s"
+    And config includes CDN_CACHE_HEADER with a value of "Surrogate-Control"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Surrogate-Control" should be "no-store"
+    And the response header "Cache-Control" should be "no-store"
+
+  Scenario: Return the calculated cache time when the release time is after the release embargo window
+    Given the "/some-path" page will have a release in the near future
+    And config includes RELEASE_EMBARGO_WINDOW with a value of "1s"
+    When the Proxy receives a GET request for "/some-path"
+    Then the max-age,s-maxage directives should be calculated, rather than predefined
+
+  Scenario: Return the default cache time when the release time is in the distant future and a release embargo window is set
+    Given the "/some-path" page will have a release in the distant future
+    And config includes RELEASE_EMBARGO_WINDOW with a value of "30s"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=900, max-age=900"
@@ -6,6 +6,7 @@ import (
 	"net/textproto"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cucumber/godog"
 )
@@ -15,6 +16,7 @@ type BabbageFeature struct {
 	Body       string
 	StatusCode int
 	Headers    map[string]string
+	Delay      time.Duration
 }
 
 func NewBabbageFeature() *BabbageFeature {
@@ -23,6 +25,8 @@ func NewBabbageFeature() *BabbageFeature {
 	}
 
 	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(f.Delay)
+
 		for headerName, headerValue := range f.Headers {
 			w.Header().Set(headerName, headerValue)
 		}
@@ -42,6 +46,7 @@ func (f *BabbageFeature) RegisterSteps(ctx *godog.ScenarioContext) {
 	ctx.Step(`^Babbage will send the following response with status "([^"]*)":$`, f.babbageWillSendTheFollowingResponseWithStatus)
 	ctx.Step(`^Babbage will set the "([^"]*)" header to "([^"]*)"$`, f.babbageWillSetTheHeaderTo)
 	ctx.Step(`^Babbage will set the HTTP status code to "([^"]*)"$`, f.babbageWillSetTheHTTPStatusCodeTo)
+	ctx.Step(`^Babbage will take "([^"]*)" to respond$`, f.babbageWillTakeToRespond)
 }
 
 func (f *BabbageFeature) babbageWillSendTheFollowingResponse(babbageBody *godog.DocString) error {
@@ -71,3 +76,14 @@ func (f *BabbageFeature) babbageWillSetTheHTTPStatusCodeTo(statusCodeStr string)
 
 	return nil
 }
+
+func (f *BabbageFeature) babbageWillTakeToRespond(delayStr string) error {
+	delay, err := time.ParseDuration(delayStr)
+	if err != nil {
+		return err
+	}
+
+	f.Delay = delay
+
+	return nil
+}
@@ -6,7 +6,10 @@ import (
 	"strings"
 	"time"
 
+	kafka "github.com/ONSdigital/dp-kafka/v4"
+	"github.com/ONSdigital/dp-kafka/v4/kafkatest"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/service"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/service/mock"
 
@@ -28,6 +31,7 @@ type Component struct {
 	legacyCacheAPIFeature   *LegacyCacheAPIFeature
 	releaseCalendarFeature  *ReleaseCalendarFeature
 	searchControllerFeature *SearchControllerFeature
+	kafkaConsumer           *kafkatest.Consumer
 }
 
 func NewComponent() (*Component, error) {
@@ -36,13 +40,15 @@ func NewComponent() (*Component, error) {
 		ServiceRunning: false,
 	}
 
-	var err error
-
-	c.Config, err = config.Get()
+	cfg, err := config.Get()
 	if err != nil {
 		return nil, err
 	}
 
+	// Each scenario gets its own copy of the config, so that changes made by one scenario don't leak into the next one
+	scenarioCfg := *cfg
+	c.Config = &scenarioCfg
+
 	c.babbageFeature = NewBabbageFeature()
 	c.datasetFeature = NewDatasetControllerFeature()
 	c.legacyCacheAPIFeature = NewLegacyCacheAPIFeature()
@@ -59,6 +65,8 @@ func NewComponent() (*Component, error) {
 	initMock := &mock.InitialiserMock{
 		DoGetHealthCheckFunc:       c.DoGetHealthcheckOk,
 		DoGetHTTPServerFunc:        c.DoGetHTTPServer,
+		DoGetKafkaConsumerFunc:     c.DoGetKafkaConsumer,
+		DoGetReleaseTimeSourceFunc: c.DoGetReleaseTimeSource,
 		DoGetRequestMiddlewareFunc: c.DoGetRequestMiddleware,
 	}
 
@@ -120,6 +128,36 @@ func (c *Component) DoGetHTTPServer(_ *config.Config, bindAddr string, router ht
 	return c.HTTPServer
 }
 
+// DoGetKafkaConsumer creates a new mock consumer every time the service is run, so that a handler can be registered
+// with it. Events are queued to the consumer of the service that was run last.
+func (c *Component) DoGetKafkaConsumer(ctx context.Context, cfg *config.Config) (kafka.IConsumerGroup, error) {
+	consumer, err := kafkatest.NewConsumer(
+		ctx,
+		&kafka.ConsumerGroupConfig{
+			BrokerAddrs:       cfg.Kafka.Addr,
+			Topic:             cfg.Kafka.ContentPublishedTopic,
+			GroupName:         cfg.Kafka.ContentPublishedGroup,
+			MinBrokersHealthy: &cfg.Kafka.ConsumerMinBrokersHealthy,
+			KafkaVersion:      &cfg.Kafka.Version,
+		},
+		&kafkatest.ConsumerConfig{
+			NumPartitions:     1,
+			ChannelBufferSize: 10,
+			InitAtCreation:    true,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c.kafkaConsumer = consumer
+	return consumer.Mock, nil
+}
+
+func (c *Component) DoGetReleaseTimeSource(cfg *config.Config) (response.ReleaseTimeSource, error) {
+	return response.NewLegacyCacheAPISource(cfg), nil
+}
+
 func (c *Component) DoGetRequestMiddleware() service.RequestMiddleware {
 	return &HTTPTestRequestMiddleware{}
 }
@@ -2,10 +2,14 @@ package steps
 
 import (
 	"fmt"
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ONSdigital/dp-legacy-cache-proxy/event"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/schema"
 	"github.com/cucumber/godog"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -38,8 +42,52 @@ func (c *Component) RegisterSteps(ctx *godog.ScenarioContext) {
 	ctx.Step(`^the Proxy receives a DELETE request for "([^"]*)"$`, c.apiFeature.IDelete)
 	ctx.Step(`^the (\S+) directives? should be calculated, rather than predefined$`, c.theDirectiveShouldBeCalculatedRatherThanPredefined)
 	ctx.Step(`^the (\S+) directive should be (\d+)$`, c.theDirectiveShouldBe)
+	ctx.Step(`^the (\S+) directive should be between (\d+) and (\d+)$`, c.theDirectiveShouldBeBetween)
+	ctx.Step(`^the Expires header should match the max-age directive$`, c.theExpiresHeaderShouldMatchTheMaxAgeDirective)
+	ctx.Step(`^the response should not have an? "([^"]*)" header$`, c.theResponseShouldNotHaveAHeader)
+	ctx.Step(`^the response header "([^"]*)" should not contain "([^"]*)"$`, c.theResponseHeaderShouldNotContain)
 	ctx.Step(`^the Proxy has the publish expiry offset disabled$`, c.disablePublishExpiryOffset)
 	ctx.Step(`^config includes ([A-Z0-9_]+) with a value of "([^"]*)"$`, c.configIncludes)
+	ctx.Step(`^Babbage is unavailable$`, c.babbageIsUnavailable)
+	ctx.Step(`^the Proxy consumes content published events$`, c.theProxyConsumesContentPublishedEvents)
+	ctx.Step(`^a content published event is received for "([^"]*)"$`, c.aContentPublishedEventIsReceivedFor)
+	ctx.Step(`^the release time cache should hold (\d+) entr(?:y|ies)$`, c.theReleaseTimeCacheShouldHoldEntries)
+}
+
+func (c *Component) theProxyConsumesContentPublishedEvents() {
+	c.Config.Kafka.ContentPublishedEnabled = true
+}
+
+func (c *Component) aContentPublishedEventIsReceivedFor(uri string) error {
+	if c.kafkaConsumer == nil {
+		return errors.New("the Proxy has not been run with a kafka consumer")
+	}
+
+	return c.kafkaConsumer.QueueMessage(schema.ContentPublishedEvent, &event.ContentPublished{URI: uri})
+}
+
+// theReleaseTimeCacheShouldHoldEntries waits for the release time cache of the service that was run last to hold the
+// expected number of entries, as the content published events are handled asynchronously
+func (c *Component) theReleaseTimeCacheShouldHoldEntries(expectedEntries int) error {
+	const timeout = 5 * time.Second
+	deadline := time.Now().Add(timeout)
+
+	for {
+		entries := c.svc.Proxy.ReleaseTimeCache().Stats().Entries
+		if entries == expectedEntries {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("expected the release time cache to hold %d entries, but it holds %d", expectedEntries, entries)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (c *Component) babbageIsUnavailable() {
+	c.babbageFeature.Server.Close()
 }
 
 func (c *Component) disablePublishExpiryOffset() {
@@ -54,18 +102,70 @@ func (c *Component) configIncludes(configItem, configVal string) error {
 			return err
 		}
 		c.Config.StaleWhileRevalidateSeconds = int64(seconds)
+	case "STALE_IF_ERROR_SECONDS":
+		seconds, err := strconv.Atoi(configVal)
+		if err != nil {
+			return err
+		}
+		c.Config.StaleIfErrorSeconds = int64(seconds)
 	case "ENABLE_MAX_AGE_COUNTDOWN":
 		isEnabled, err := strconv.ParseBool(configVal)
 		if err != nil {
 			return err
 		}
 		c.Config.EnableMaxAgeCountdown = isEnabled
+	case "BABBAGE_TIMEOUT":
+		timeout, err := time.ParseDuration(configVal)
+		if err != nil {
+			return err
+		}
+		c.Config.BabbageTransport.Timeout = timeout
 	case "ENABLE_SEARCH_CONTROLLER":
 		isEnabled, err := strconv.ParseBool(configVal)
 		if err != nil {
 			return err
 		}
 		c.Config.EnableSearchController = isEnabled
+	case "ENABLE_ERROR_RESPONSE_CACHING":
+		isEnabled, err := strconv.ParseBool(configVal)
+		if err != nil {
+			return err
+		}
+		c.Config.EnableErrorResponseCaching = isEnabled
+	case "CDN_CACHE_HEADER":
+		c.Config.CDNCacheHeader = configVal
+	case "CACHE_TIME_BROWSER":
+		cacheTime, err := time.ParseDuration(configVal)
+		if err != nil {
+			return err
+		}
+		c.Config.CacheTimeBrowser = cacheTime
+	case "RELEASE_EMBARGO_WINDOW":
+		window, err := time.ParseDuration(configVal)
+		if err != nil {
+			return err
+		}
+		c.Config.ReleaseEmbargoWindow = window
+	case "CACHE_TIME_JITTER_PERCENT":
+		percent, err := strconv.ParseFloat(configVal, 64)
+		if err != nil {
+			return err
+		}
+		c.Config.CacheTimeJitterPercent = percent
+	case "UPSTREAM_CACHE_DIRECTIVES":
+		c.Config.UpstreamCacheDirectives = strings.Split(configVal, ",")
+	case "SURROGATE_KEY_HEADER":
+		c.Config.SurrogateKeyHeader = configVal
+	case "ENVIRONMENT":
+		c.Config.Environment = configVal
+	case "ENABLE_TIME_TRAVEL":
+		isEnabled, err := strconv.ParseBool(configVal)
+		if err != nil {
+			return err
+		}
+		c.Config.EnableTimeTravel = isEnabled
+	case "ADMIN_AUTH_TOKEN":
+		c.Config.AdminAuthToken = configVal
 	default:
 		return fmt.Errorf("not a valid config item")
 	}
@@ -119,6 +219,10 @@ func (c *Component) getMaxAgeAndServerMaxAge() (maxAge, serverMaxAge int, err er
 
 	maxAgeMatch := reMaxAge.FindStringSubmatch(cacheControl)
 	serverMaxAgeMatch := reServerMaxAge.FindStringSubmatch(cacheControl)
+	// When a CDN cache header is configured, the CDN's max-age is in that header rather than the s-maxage directive
+	if c.Config.CDNCacheHeader != "" {
+		serverMaxAgeMatch = reMaxAge.FindStringSubmatch(c.apiFeature.HTTPResponse.Header.Get(c.Config.CDNCacheHeader))
+	}
 
 	maxAgeFound := assert.GreaterOrEqual(c, len(maxAgeMatch), 1)
 	if !maxAgeFound {
@@ -138,6 +242,42 @@ func (c *Component) getMaxAgeAndServerMaxAge() (maxAge, serverMaxAge int, err er
 	return
 }
 
+func (c *Component) theExpiresHeaderShouldMatchTheMaxAgeDirective() error {
+	maxAge, _, err := c.getMaxAgeAndServerMaxAge()
+	if err != nil {
+		return err
+	}
+
+	date, err := http.ParseTime(c.apiFeature.HTTPResponse.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("the Date header is invalid: %w", err)
+	}
+	expires, err := http.ParseTime(c.apiFeature.HTTPResponse.Header.Get("Expires"))
+	if err != nil {
+		return fmt.Errorf("the Expires header is invalid: %w", err)
+	}
+
+	// The release time that the max-age counts down to may be up to a second after the max-age, which is truncated
+	if lifetime := expires.Sub(date); lifetime < time.Duration(maxAge)*time.Second || lifetime > time.Duration(maxAge+1)*time.Second {
+		return fmt.Errorf("the Expires header (%s) is %s after the Date header, but the max-age is %d", expires, lifetime, maxAge)
+	}
+	return nil
+}
+
+func (c *Component) theResponseHeaderShouldNotContain(name, value string) error {
+	if header := c.apiFeature.HTTPResponse.Header.Get(name); strings.Contains(header, value) {
+		return fmt.Errorf("the %s header (%q) should not contain %q", name, header, value)
+	}
+	return nil
+}
+
+func (c *Component) theResponseShouldNotHaveAHeader(name string) error {
+	if values := c.apiFeature.HTTPResponse.Header.Values(name); len(values) > 0 {
+		return fmt.Errorf("the %s header should not be set, but it is %q", name, values)
+	}
+	return nil
+}
+
 func (c *Component) checkMaxAgeAndServerMaxAge(checkMaxAgeCalculated, checkServerMaxAgeCalculated bool) error {
 	maxAge, serverMaxAge, err := c.getMaxAgeAndServerMaxAge()
 	if err != nil {
@@ -149,6 +289,9 @@ func (c *Component) checkMaxAgeAndServerMaxAge(checkMaxAgeCalculated, checkServe
 		int(c.Config.CacheTimeErrored.Seconds()),
 		int(c.Config.CacheTimeLong.Seconds()),
 		int(c.Config.CacheTimeShort.Seconds()),
+		int(c.Config.CacheTimeNotFound.Seconds()),
+		int(c.Config.CacheTimePermanentRedirect.Seconds()),
+		int(c.Config.CacheTimeTemporaryRedirect.Seconds()),
 	}
 
 	type testDirectives struct {
@@ -200,23 +343,40 @@ func (c *Component) theDirectiveShouldBeCalculatedRatherThanPredefined(directive
 }
 
 func (c *Component) theDirectiveShouldBe(directiveName string, expectedValue int) error {
-	maxAge, serverMaxAge, err := c.getMaxAgeAndServerMaxAge()
+	obtainedValue, err := c.getDirective(directiveName)
 	if err != nil {
 		return err
 	}
-	var obtainedValue int
+	if obtainedValue != expectedValue {
+		return fmt.Errorf("%s (%d) does not match expected (%d)", directiveName, obtainedValue, expectedValue)
+	}
+	return nil
+}
+
+func (c *Component) theDirectiveShouldBeBetween(directiveName string, lowerBound, upperBound int) error {
+	obtainedValue, err := c.getDirective(directiveName)
+	if err != nil {
+		return err
+	}
+	if obtainedValue < lowerBound || obtainedValue > upperBound {
+		return fmt.Errorf("%s (%d) is not between %d and %d", directiveName, obtainedValue, lowerBound, upperBound)
+	}
+	return nil
+}
+
+func (c *Component) getDirective(directiveName string) (int, error) {
+	maxAge, serverMaxAge, err := c.getMaxAgeAndServerMaxAge()
+	if err != nil {
+		return 0, err
+	}
 	switch directiveName {
 	case maxAgeDirective:
-		obtainedValue = maxAge
+		return maxAge, nil
 	case serverMaxAgeDirective:
-		obtainedValue = serverMaxAge
+		return serverMaxAge, nil
 	default:
-		return fmt.Errorf("did not recognise directive %q", directiveName)
+		return 0, fmt.Errorf("did not recognise directive %q", directiveName)
 	}
-	if obtainedValue != expectedValue {
-		return fmt.Errorf("%s (%d) does not match expected (%d)", directiveName, obtainedValue, expectedValue)
-	}
-	return nil
 }
 
 // shouldEvaluateHeader helps determine which headers should be skipped when comparing the Babbage and the Proxy response
@@ -0,0 +1,56 @@
+Feature: Surrogate keys
+
+  When a surrogate key header is configured, the proxy tags every response whose cache headers it sets with the page's
+  path and the ID of its Cache Time resource, so that publishing can purge every response belonging to a page from the
+  CDN in one call. Surrogate-Key values are separated by spaces and Cache-Tag values by commas. The CDN strips the
+  header before the response leaves the edge.
+
+  Background:
+    Given Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+
+  Scenario: No surrogate key header is configured
+    When the Proxy receives a GET request for "/some-path"
+    Then the response should not have a "Surrogate-Key" header
+    And the response should not have a "Cache-Tag" header
+
+  Scenario: The Surrogate-Key header is configured
+    Given config includes SURROGATE_KEY_HEADER with a value of "Surrogate-Key"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Surrogate-Key" should be "/some-path 3438421d8c85f93e98c1688bf3172228"
+
+  Scenario: The Cache-Tag header is configured
+    Given config includes SURROGATE_KEY_HEADER with a value of "Cache-Tag"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Tag" should be "/some-path,3438421d8c85f93e98c1688bf3172228"
+
+  Scenario: A response with a fixed cache time is tagged
+    Given config includes SURROGATE_KEY_HEADER with a value of "Surrogate-Key"
+    And Babbage will send the following response with status "301":
+      """
+      """
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Surrogate-Key" should be "/some-path 3438421d8c85f93e98c1688bf3172228"
+
+  Scenario: A response within the release embargo window is tagged
+    Given config includes SURROGATE_KEY_HEADER with a value of "Surrogate-Key"
+    And config includes RELEASE_EMBARGO_WINDOW with a value of "30s"
+    And the "/some-path" page will have a release in the near future
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "no-store"
+    And the response header "Surrogate-Key" should be "/some-path 3438421d8c85f93e98c1688bf3172228"
+
+  Scenario: A response that the proxy does not modify is tagged if the CDN may store it
+    Given config includes SURROGATE_KEY_HEADER with a value of "Surrogate-Key"
+    And Babbage will set the "Cache-Control" header to "public, max-age=60"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, max-age=60"
+    And the response header "Surrogate-Key" should be "/some-path 3438421d8c85f93e98c1688bf3172228"
+
+  Scenario: A response that the CDN cannot store is not tagged
+    Given config includes SURROGATE_KEY_HEADER with a value of "Surrogate-Key"
+    And Babbage will set the "Cache-Control" header to "no-store"
+    When the Proxy receives a GET request for "/some-path"
+    Then the response should not have a "Surrogate-Key" header
@@ -0,0 +1,71 @@
+Feature: Time travel
+
+  In a pre-production environment, time travel can be enabled to rehearse a release. The time that the cache headers
+  are calculated from is then shifted for a single request by the X-Time-Travel header, or for every request by the
+  admin clock endpoint. Both require the admin auth token. The page below was released at midnight on 1 January 1980.
+
+  Background:
+    Given Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+    And the "/some-path" page was released long ago
+    And config includes ENVIRONMENT with a value of "staging"
+    And config includes ADMIN_AUTH_TOKEN with a value of "some-token"
+
+  Scenario: The max-age counts down to the release when the request travels to before it
+    Given config includes ENABLE_TIME_TRAVEL with a value of "true"
+    When I set the "Authorization" header to "Bearer some-token"
+    And I set the "X-Time-Travel" header to "1979-12-31T23:59:30Z"
+    And the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=30, max-age=30"
+    And the response header "Expires" should be "Tue, 01 Jan 1980 00:00:00 GMT"
+
+  Scenario: The short cache time is used when the request travels to just after the release
+    Given config includes ENABLE_TIME_TRAVEL with a value of "true"
+    When I set the "Authorization" header to "Bearer some-token"
+    And I set the "X-Time-Travel" header to "1980-01-01T00:01:00Z"
+    And the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=10, max-age=10"
+
+  Scenario: The upstream error response is cached as of the time that the request travels to
+    Given Babbage is unavailable
+    And config includes ENABLE_TIME_TRAVEL with a value of "true"
+    When I set the "Authorization" header to "Bearer some-token"
+    And I set the "X-Time-Travel" header to "1979-12-31T23:59:30Z"
+    And the Proxy receives a GET request for "/some-path"
+    Then the HTTP status code should be "502"
+    And the response header "Cache-Control" should be "public, s-maxage=30, max-age=30"
+    And the response header "Date" should be "Mon, 31 Dec 1979 23:59:30 GMT"
+    And the response header "Expires" should be "Tue, 01 Jan 1980 00:00:00 GMT"
+
+  Scenario: The time travel header is ignored without the admin auth token
+    Given config includes ENABLE_TIME_TRAVEL with a value of "true"
+    When I set the "X-Time-Travel" header to "1979-12-31T23:59:30Z"
+    And the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=900, max-age=900"
+
+  Scenario: The time travel header is ignored when time travel is disabled
+    When I set the "Authorization" header to "Bearer some-token"
+    And I set the "X-Time-Travel" header to "1979-12-31T23:59:30Z"
+    And the Proxy receives a GET request for "/some-path"
+    Then the response header "Cache-Control" should be "public, s-maxage=900, max-age=900"
+
+  Scenario: The admin clock endpoint shifts the clock
+    Given config includes ENABLE_TIME_TRAVEL with a value of "true"
+    When I set the "Authorization" header to "Bearer some-token"
+    And I PUT "/admin/clock"
+      """
+      {"now": "1979-12-31T23:59:00Z"}
+      """
+    Then the HTTP status code should be "200"
+    And the response header "Cache-Control" should be "no-store"
+    And the response header "Content-Type" should be "application/json"
+
+  Scenario: The admin clock endpoint is not added when time travel is disabled
+    When I set the "Authorization" header to "Bearer some-token"
+    And the Proxy receives a GET request for "/admin/clock"
+    Then I should receive the following response:
+      """
+      Mock response from Babbage
+      """
@@ -87,8 +87,11 @@ Feature: Unmodified response
     When the Proxy receives a GET request for "/some-url"
     Then I should receive the same, unmodified response from Babbage
   Examples:
-    | cache-control          |
-    | max-age=123            |
-    | no-cache               |
-    | no-store               |
-    | public, max-age=112233 |
+    | cache-control             |
+    | max-age=123               |
+    | no-cache                  |
+    | no-store                  |
+    | public, max-age=112233    |
+    | Public, Max-Age=112233    |
+    | s-maxage=123              |
+    | must-understand, no-store |
@@ -0,0 +1,33 @@
+Feature: Upstream error response
+
+  When the request to the upstream service fails, the proxy returns a 502 Bad Gateway (if the upstream could not be
+  reached) or a 504 Gateway Timeout (if the upstream took too long to respond). The response names the upstream that
+  failed and can only be cached for the errored cache time.
+
+  Scenario: Babbage cannot be reached
+    Given Babbage is unavailable
+    When the Proxy receives a GET request for "/some-path"
+    Then the HTTP status code should be "502"
+    And the response header "X-Upstream-Error" should be "babbage"
+    And the response header "Cache-Control" should be "public, s-maxage=30, max-age=30"
+    And the Expires header should match the max-age directive
+    And I should receive the following response:
+      """
+      Bad Gateway: the upstream service failed to respond
+      """
+
+  Scenario: Babbage takes too long to respond
+    Given Babbage will send the following response:
+      """
+      Mock response from Babbage
+      """
+    And Babbage will take "500ms" to respond
+    And config includes BABBAGE_TIMEOUT with a value of "100ms"
+    When the Proxy receives a GET request for "/some-path"
+    Then the HTTP status code should be "504"
+    And the response header "X-Upstream-Error" should be "babbage"
+    And the response header "Cache-Control" should be "public, s-maxage=30, max-age=30"
+    And I should receive the following response:
+      """
+      Gateway Timeout: the upstream service failed to respond
+      """
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	viaPseudonym = "dp-legacy-cache-proxy"
+
+	forwardedHeader       = "Forwarded"
+	xForwardedForHeader   = "X-Forwarded-For"
+	xForwardedHostHeader  = "X-Forwarded-Host"
+	xForwardedProtoHeader = "X-Forwarded-Proto"
+	viaHeader             = "Via"
+)
+
+// setForwardingHeaders adds the X-Forwarded-*, Forwarded (RFC 7239) and Via (RFC 7230) headers to the proxy request.
+// When the forwarding headers sent by the client are not trusted, they are discarded and replaced with the ones
+// describing the connection to this proxy.
+func setForwardingHeaders(proxyReq, req *http.Request, trustForwardedHeaders bool) {
+	header := proxyReq.Header
+
+	if !trustForwardedHeaders {
+		header.Del(forwardedHeader)
+		header.Del(xForwardedForHeader)
+		header.Del(xForwardedHostHeader)
+		header.Del(xForwardedProtoHeader)
+	}
+
+	proto := "http"
+	if req.TLS != nil {
+		proto = "https"
+	}
+
+	clientIP := getClientIP(req)
+	if clientIP != "" {
+		appendHeaderValue(header, xForwardedForHeader, clientIP)
+	}
+
+	if header.Get(xForwardedHostHeader) == "" && req.Host != "" {
+		header.Set(xForwardedHostHeader, req.Host)
+	}
+
+	if header.Get(xForwardedProtoHeader) == "" {
+		header.Set(xForwardedProtoHeader, proto)
+	}
+
+	appendHeaderValue(header, forwardedHeader, forwardedElement(clientIP, req.Host, proto))
+	appendHeaderValue(header, viaHeader, fmt.Sprintf("%d.%d %s", req.ProtoMajor, req.ProtoMinor, viaPseudonym))
+}
+
+func getClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return ""
+	}
+
+	return host
+}
+
+// forwardedElement builds a single element of the Forwarded header, such as `for=192.0.2.60;host=example.com;proto=http`
+func forwardedElement(clientIP, host, proto string) string {
+	var pairs []string
+
+	if clientIP != "" {
+		if strings.Contains(clientIP, ":") {
+			// IPv6 addresses must be enclosed in square brackets and quoted
+			clientIP = "[" + clientIP + "]"
+		}
+		pairs = append(pairs, "for="+quoteForwardedValue(clientIP))
+	}
+
+	if host != "" {
+		pairs = append(pairs, "host="+quoteForwardedValue(host))
+	}
+
+	pairs = append(pairs, "proto="+proto)
+
+	return strings.Join(pairs, ";")
+}
+
+// quoteForwardedValue returns the value as a quoted-string if it contains any characters that are not allowed in a token
+func quoteForwardedValue(value string) string {
+	if strings.ContainsAny(value, `:[]"\ ,;=`) {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+
+	return value
+}
+
+func appendHeaderValue(header http.Header, name, value string) {
+	if previousValues := header.Values(name); len(previousValues) > 0 {
+		value = strings.Join(previousValues, ", ") + ", " + value
+	}
+
+	header.Set(name, value)
+}
@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSetForwardingHeaders(t *testing.T) {
+	Convey("Given a request sent through the Frontend Router", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/some-path", http.NoBody)
+		req.RemoteAddr = "10.0.0.1:12345"
+		req.Host = "www.ons.gov.uk"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.Header.Set("X-Forwarded-Host", "ons.gov.uk")
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("Forwarded", "for=203.0.113.7;proto=https")
+		req.Header.Set("Via", "1.1 router")
+
+		Convey("When the forwarding headers are trusted", func() {
+			proxyReq := &http.Request{Header: req.Header.Clone()}
+			setForwardingHeaders(proxyReq, req, true)
+
+			Convey("Then the incoming values are kept and appended to", func() {
+				So(proxyReq.Header.Get("X-Forwarded-For"), ShouldEqual, "203.0.113.7, 10.0.0.1")
+				So(proxyReq.Header.Get("X-Forwarded-Host"), ShouldEqual, "ons.gov.uk")
+				So(proxyReq.Header.Get("X-Forwarded-Proto"), ShouldEqual, "https")
+				So(proxyReq.Header.Get("Forwarded"), ShouldEqual, "for=203.0.113.7;proto=https, for=10.0.0.1;host=www.ons.gov.uk;proto=http")
+				So(proxyReq.Header.Get("Via"), ShouldEqual, "1.1 router, 1.1 dp-legacy-cache-proxy")
+			})
+		})
+
+		Convey("When the forwarding headers are not trusted", func() {
+			proxyReq := &http.Request{Header: req.Header.Clone()}
+			setForwardingHeaders(proxyReq, req, false)
+
+			Convey("Then the incoming values are replaced", func() {
+				So(proxyReq.Header.Get("X-Forwarded-For"), ShouldEqual, "10.0.0.1")
+				So(proxyReq.Header.Get("X-Forwarded-Host"), ShouldEqual, "www.ons.gov.uk")
+				So(proxyReq.Header.Get("X-Forwarded-Proto"), ShouldEqual, "http")
+				So(proxyReq.Header.Get("Forwarded"), ShouldEqual, "for=10.0.0.1;host=www.ons.gov.uk;proto=http")
+				So(proxyReq.Header.Get("Via"), ShouldEqual, "1.1 router, 1.1 dp-legacy-cache-proxy")
+			})
+		})
+	})
+
+	Convey("Given a request over TLS from an IPv6 client to a host with a port", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/some-path", http.NoBody)
+		req.RemoteAddr = "[2001:db8::1]:12345"
+		req.Host = "localhost:29200"
+		req.TLS = &tls.ConnectionState{}
+
+		Convey("When the forwarding headers are set", func() {
+			proxyReq := &http.Request{Header: http.Header{}}
+			setForwardingHeaders(proxyReq, req, true)
+
+			Convey("Then the Forwarded values are quoted and the protocol is https", func() {
+				So(proxyReq.Header.Get("X-Forwarded-For"), ShouldEqual, "2001:db8::1")
+				So(proxyReq.Header.Get("X-Forwarded-Proto"), ShouldEqual, "https")
+				So(proxyReq.Header.Get("Forwarded"), ShouldEqual, `for="[2001:db8::1]";host="localhost:29200";proto=https`)
+			})
+		})
+	})
+}
+
+func TestProxyForwardedRequestHeaders(t *testing.T) {
+	Convey("Given a Proxy and a Babbage server that records the request headers", t, func() {
+		var babbageRequestHeaders http.Header
+		mockBabbageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			babbageRequestHeaders = r.Header
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer mockBabbageServer.Close()
+		cfg := &config.Config{BabbageURL: mockBabbageServer.URL, TrustForwardedHeaders: true}
+		legacyCacheProxy, err := Setup(context.Background(), mux.NewRouter(), cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
+
+		Convey("When a request with hop-by-hop headers is sent", func() {
+			r := httptest.NewRequest(http.MethodGet, "/test-endpoint", http.NoBody)
+			r.Header.Set("Connection", "X-Custom-Hop")
+			r.Header.Set("X-Custom-Hop", "value")
+			r.Header.Set("Keep-Alive", "timeout=5")
+			r.Header.Set("Upgrade", "websocket")
+			r.Header.Set("Accept", "text/html")
+			legacyCacheProxy.Router.ServeHTTP(httptest.NewRecorder(), r)
+
+			Convey("Then the hop-by-hop headers are not forwarded to Babbage", func() {
+				So(babbageRequestHeaders.Get("X-Custom-Hop"), ShouldBeEmpty)
+				So(babbageRequestHeaders.Get("Keep-Alive"), ShouldBeEmpty)
+				So(babbageRequestHeaders.Get("Upgrade"), ShouldBeEmpty)
+				So(babbageRequestHeaders.Get("Accept"), ShouldEqual, "text/html")
+			})
+
+			Convey("Then the forwarding headers are sent to Babbage", func() {
+				So(babbageRequestHeaders.Get("X-Forwarded-For"), ShouldEqual, "192.0.2.1")
+				So(babbageRequestHeaders.Get("Via"), ShouldEqual, "1.1 dp-legacy-cache-proxy")
+			})
+
+			Convey("Then the original request headers are left untouched", func() {
+				So(r.Header.Get("X-Custom-Hop"), ShouldEqual, "value")
+				So(r.Header.Get("X-Forwarded-For"), ShouldBeEmpty)
+			})
+		})
+	})
+}
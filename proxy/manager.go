@@ -3,8 +3,7 @@ package proxy
 import (
 	"context"
 	"net/http"
-	"net/url"
-	"strings"
+	"time"
 
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
@@ -12,33 +11,49 @@ import (
 )
 
 func (proxy *Proxy) manage(ctx context.Context, w http.ResponseWriter, req *http.Request, cfg *config.Config) {
-	pageType := req.Header.Get("Ons-Page-Type")
-	targetURL := getTargetURL(req.URL.String(), pageType, cfg)
+	target, isAllowed := proxy.allowTarget(ctx, proxy.RoutingTable.Match(req), cfg)
+	if !isAllowed {
+		response.WriteUpstreamError(ctx, w, req, target.Upstream, cfg.CircuitBreaker.OpenStatusCode, cfg, proxy.clock)
+		return
+	}
+	circuitBreaker := proxy.circuitBreakers[target.Upstream]
+
+	targetURL := target.URL + req.URL.String()
+	log.Info(ctx, "forwarding request to upstream", log.Data{"upstream": target.Upstream})
 
 	proxyReq, err := http.NewRequestWithContext(ctx, req.Method, targetURL, req.Body) //nolint:gosec // we control the URLs so not technically as tainted as it suggests
 
 	if err != nil {
 		log.Error(ctx, "error creating the proxy request", err)
+		circuitBreaker.Release()
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	// Copy headers from original request to proxy request
-	proxyReq.Header = req.Header
+	// Copy headers from original request to proxy request, apart from the hop-by-hop ones
+	proxyReq.Header = req.Header.Clone()
+	response.RemoveHopByHopHeaders(proxyReq.Header)
+	setForwardingHeaders(proxyReq, req, cfg.TrustForwardedHeaders)
 	// Also copy Host (header had been removed from original request)
 	proxyReq.Host = req.Host
 
-	client := &http.Client{
-		// nolint:revive // param names give context here.
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
+	startedAt := time.Now()
+	serviceResponse, err := proxy.do(ctx, target.Upstream, proxyReq, cfg)
+	if isClientCancellation(req, err) {
+		// The client went away before the upstream responded, which says nothing about the upstream
+		circuitBreaker.Release()
+	} else {
+		circuitBreaker.Record(ctx, isUpstreamFailure(req, serviceResponse, err), time.Since(startedAt))
 	}
-	serviceResponse, err := client.Do(proxyReq) //nolint:gosec // we control the URLs so not technically as tainted as it suggests
 
 	if err != nil {
-		log.Error(ctx, "error sending the proxy request", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		statusCode := upstreamErrorStatusCode(err)
+		log.Error(ctx, "error sending the proxy request", err, log.Data{"upstream": target.Upstream, "status_code": statusCode})
+		if statusCode == http.StatusInternalServerError {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		response.WriteUpstreamError(ctx, w, req, target.Upstream, statusCode, cfg, proxy.clock)
 		return
 	}
 
@@ -48,33 +63,25 @@ func (proxy *Proxy) manage(ctx context.Context, w http.ResponseWriter, req *http
 		}
 	}()
 
-	response.WriteResponse(ctx, w, serviceResponse, req, cfg)
-}
-
-func IsReleaseCalendarURL(requestURLstring string) bool {
-	return strings.HasPrefix(requestURLstring, "/releases/")
+	response.WriteResponse(ctx, w, serviceResponse, req, target.Upstream, cfg, proxy.cachePolicy, proxy.releaseTimes, proxy.clock)
 }
 
-func IsSearchControllerURL(requestURLstring string) bool {
-	requestURL, err := url.Parse(requestURLstring)
-	if err != nil {
-		return false
+// allowTarget checks the circuit breaker of the target's upstream. If the circuit is open, the request falls back to
+// Babbage when both the route and the configuration allow it, otherwise it must fail fast.
+func (proxy *Proxy) allowTarget(ctx context.Context, target Target, cfg *config.Config) (Target, bool) {
+	if proxy.circuitBreakers[target.Upstream].Allow(ctx) {
+		return target, true
 	}
-	return (strings.HasSuffix(requestURL.EscapedPath(), "/previousreleases") || strings.HasSuffix(requestURL.EscapedPath(), "/relatedData") || strings.HasSuffix(requestURL.EscapedPath(), "/relateddata"))
-}
 
-// IsDatasetLandingPage checks if the page type is a dataset_landing_page
-func IsDatasetLandingPage(pageTypeString string) bool {
-	return pageTypeString == "dataset_landing_page"
-}
+	logData := log.Data{"upstream": target.Upstream}
 
-func getTargetURL(requestURL, pageType string, cfg *config.Config) string {
-	if IsReleaseCalendarURL(requestURL) {
-		return cfg.RelCalURL + requestURL
-	} else if IsSearchControllerURL(requestURL) && cfg.EnableSearchController {
-		return cfg.SearchControllerURL + requestURL
-	} else if IsDatasetLandingPage(pageType) {
-		return cfg.DatasetControllerURL + requestURL
+	if !cfg.CircuitBreaker.BabbageFallback || !target.BabbageFallback {
+		log.Warn(ctx, "circuit is open, failing fast", logData)
+		return target, false
 	}
-	return cfg.BabbageURL + requestURL
+
+	log.Warn(ctx, "circuit is open, falling back to babbage", logData)
+	babbageTarget := proxy.RoutingTable.Resolve(UpstreamBabbage)
+
+	return babbageTarget, proxy.circuitBreakers[UpstreamBabbage].Allow(ctx)
 }
@@ -5,36 +5,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/ONSdigital/dp-legacy-cache-proxy/circuitbreaker"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
 	"github.com/gorilla/mux"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
-var (
-	releaseCalendarURLs = []string{
-		"/releases/greenjobscurrentandupcomingworkmarch2022",
-		"/releases/post2019westminsterparliamentaryconstituenciesandseneddelectoralregionsdataenglandandwalescensus2021",
-		"/releases/mycollectionpage1",
-		"/releases/timespentinnature",
-		"/releases/constructionstatisticsgreatbritain2022",
-	}
-
-	babbageURLs = []string{
-		"/visualisations/dvc1945/seasonalflu/index.html",
-		"/generator?uri=/economy/economicoutputandproductivity/output/bulletins/economicactivityandsocialchangeintheukrealtimeindicators/15february2024/426e63a0&format=csv",
-		"/economy/inflationandpriceindices/bulletins/producerpriceinflation/latest",
-		"/economy/grossdomesticproductgdp/timeseries/abmi/pn2/linechartconfig",
-		"/businessindustryandtrade/changestobusiness/mergersandacquisitions/datasets/timeseries/15march2024",
-	}
-
-	searchControllerURLs = []string{
-		"/economy/previousreleases",
-		"/businessindustryandtrade/changestobusiness/mergersandacquisitions/relatedData",
-		"/economy/economicoutputandproductivity/output/bulletins/economicactivityandsocialchangeintheukrealtimeindicators/relateddata",
-	}
-)
-
 func TestProxyHandleRequestOK(t *testing.T) {
 	Convey("Given a Proxy and a Babbage server", t, func() {
 		mockBabbageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -50,7 +29,8 @@ func TestProxyHandleRequestOK(t *testing.T) {
 		router := mux.NewRouter()
 		cfg := &config.Config{BabbageURL: mockBabbageServer.URL}
 
-		legacyCacheProxy := Setup(ctx, router, cfg)
+		legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
 
 		Convey("When a request is sent", func() {
 			w := httptest.NewRecorder()
@@ -82,7 +62,8 @@ func TestProxyHandleRedirect(t *testing.T) {
 		router := mux.NewRouter()
 		cfg := &config.Config{BabbageURL: mockBabbageServer.URL}
 
-		legacyCacheProxy := Setup(ctx, router, cfg)
+		legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
 
 		Convey("When a request to /ons/* is sent", func() {
 			w := httptest.NewRecorder()
@@ -103,7 +84,8 @@ func TestProxyHandleRequestError(t *testing.T) {
 		ctx := context.Background()
 		router := mux.NewRouter()
 		cfg := &config.Config{BabbageURL: "invalid-babbage-url"}
-		legacyCacheProxy := Setup(ctx, router, cfg)
+		legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
 
 		Convey("When a request is sent", func() {
 			w := httptest.NewRecorder()
@@ -117,6 +99,205 @@ func TestProxyHandleRequestError(t *testing.T) {
 	})
 }
 
+func TestProxyHandleUpstreamFailure(t *testing.T) {
+	Convey("Given a Proxy with a Babbage server that is not reachable", t, func() {
+		unreachableBabbageServer := httptest.NewServer(http.NotFoundHandler())
+		unreachableBabbageServer.Close()
+		ctx := context.Background()
+		router := mux.NewRouter()
+		cfg := &config.Config{BabbageURL: unreachableBabbageServer.URL, CacheTimeErrored: 30 * time.Second}
+		legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
+
+		Convey("When a request is sent", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/test-endpoint", http.NoBody)
+			legacyCacheProxy.Router.ServeHTTP(w, r)
+
+			Convey("Then the proxy should return a 502 Bad Gateway naming the upstream with an errored cache time", func() {
+				So(w.Code, ShouldEqual, http.StatusBadGateway)
+				So(w.Header().Get(response.UpstreamErrorHeader), ShouldEqual, UpstreamBabbage)
+				So(w.Header().Get("Cache-Control"), ShouldEqual, "public, s-maxage=30, max-age=30")
+				So(w.Body.String(), ShouldEqual, "Bad Gateway: the upstream service failed to respond\n")
+			})
+		})
+	})
+
+	Convey("Given a Proxy with a Babbage server that takes too long to respond", t, func() {
+		slowBabbageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer slowBabbageServer.Close()
+		ctx := context.Background()
+		router := mux.NewRouter()
+		cfg := &config.Config{
+			BabbageURL:       slowBabbageServer.URL,
+			BabbageTransport: config.UpstreamTransport{Timeout: 50 * time.Millisecond},
+			CacheTimeErrored: 30 * time.Second,
+		}
+		legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
+
+		Convey("When a request is sent", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/test-endpoint", http.NoBody)
+			legacyCacheProxy.Router.ServeHTTP(w, r)
+
+			Convey("Then the proxy should return a 504 Gateway Timeout naming the upstream with an errored cache time", func() {
+				So(w.Code, ShouldEqual, http.StatusGatewayTimeout)
+				So(w.Header().Get(response.UpstreamErrorHeader), ShouldEqual, UpstreamBabbage)
+				So(w.Header().Get("Cache-Control"), ShouldEqual, "public, s-maxage=30, max-age=30")
+				So(w.Body.String(), ShouldEqual, "Gateway Timeout: the upstream service failed to respond\n")
+			})
+		})
+	})
+}
+
+func TestProxyHandleClientCancellation(t *testing.T) {
+	Convey("Given a Proxy with a circuit breaker that opens after a single failure", t, func() {
+		mockBabbageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer mockBabbageServer.Close()
+		ctx := context.Background()
+		router := mux.NewRouter()
+		cfg := &config.Config{
+			BabbageURL: mockBabbageServer.URL,
+			CircuitBreaker: config.UpstreamCircuitBreaker{
+				CircuitBreaker: config.CircuitBreaker{
+					Enabled:      true,
+					FailureRatio: 0.5,
+					MinRequests:  1,
+					Window:       time.Minute,
+					OpenDuration: time.Minute,
+				},
+				BabbageEnabled: true,
+			},
+		}
+
+		legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
+
+		Convey("When the client goes away before Babbage responds", func() {
+			cancelledCtx, cancel := context.WithCancel(ctx)
+			cancel()
+			r := httptest.NewRequest(http.MethodGet, "/test-endpoint", http.NoBody).WithContext(cancelledCtx)
+			legacyCacheProxy.Router.ServeHTTP(httptest.NewRecorder(), r)
+
+			Convey("Then Babbage's circuit stays closed", func() {
+				So(legacyCacheProxy.CircuitBreakers()[UpstreamBabbage].State(), ShouldEqual, circuitbreaker.Closed)
+			})
+		})
+	})
+}
+
+func TestProxyHandleFailingBabbage(t *testing.T) {
+	Convey("Given a Proxy with the default circuit breaker configuration and a failing Babbage", t, func() {
+		babbageRequests := 0
+		failingBabbageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			babbageRequests++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer failingBabbageServer.Close()
+		ctx := context.Background()
+		router := mux.NewRouter()
+		defaultCfg, err := config.Get()
+		So(err, ShouldBeNil)
+		cfg := &config.Config{BabbageURL: failingBabbageServer.URL, CircuitBreaker: defaultCfg.CircuitBreaker}
+
+		legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
+
+		Convey("When more requests than the circuit breaker's minimum fail", func() {
+			requests := 2 * cfg.CircuitBreaker.MinRequests
+			for i := 0; i < requests; i++ {
+				legacyCacheProxy.Router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/economy", http.NoBody))
+			}
+
+			Convey("Then every request is still sent to Babbage rather than failing fast", func() {
+				So(babbageRequests, ShouldEqual, requests)
+				So(legacyCacheProxy.CircuitBreakers()[UpstreamBabbage].State(), ShouldEqual, circuitbreaker.Closed)
+			})
+		})
+	})
+}
+
+func TestProxyHandleOpenCircuit(t *testing.T) {
+	Convey("Given a Proxy whose Dataset Controller circuit is open", t, func() {
+		mockBabbageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte("Mock Babbage Response"))
+			if err != nil {
+				panic(err)
+			}
+		}))
+		defer mockBabbageServer.Close()
+		failingDatasetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer failingDatasetServer.Close()
+		ctx := context.Background()
+		router := mux.NewRouter()
+		cfg := &config.Config{
+			BabbageURL:           mockBabbageServer.URL,
+			DatasetControllerURL: failingDatasetServer.URL,
+			CacheTimeErrored:     30 * time.Second,
+			CircuitBreaker: config.UpstreamCircuitBreaker{
+				CircuitBreaker: config.CircuitBreaker{
+					Enabled:      true,
+					FailureRatio: 0.5,
+					MinRequests:  1,
+					Window:       time.Minute,
+					OpenDuration: time.Minute,
+				},
+				OpenStatusCode: http.StatusServiceUnavailable,
+			},
+		}
+
+		sendDatasetRequest := func(proxy *Proxy) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/economy/dataset", http.NoBody)
+			r.Header.Set("Ons-Page-Type", "dataset_landing_page")
+			proxy.Router.ServeHTTP(w, r)
+			return w
+		}
+
+		Convey("And the fallback to Babbage is disabled", func() {
+			legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+			So(err, ShouldBeNil)
+			So(sendDatasetRequest(legacyCacheProxy).Code, ShouldEqual, http.StatusServiceUnavailable)
+			So(legacyCacheProxy.CircuitBreakers()[UpstreamDatasetController].State(), ShouldEqual, circuitbreaker.Open)
+
+			Convey("When a dataset landing page is requested", func() {
+				w := sendDatasetRequest(legacyCacheProxy)
+
+				Convey("Then the proxy fails fast with the configured status naming the upstream", func() {
+					So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+					So(w.Header().Get(response.UpstreamErrorHeader), ShouldEqual, UpstreamDatasetController)
+					So(w.Body.String(), ShouldEqual, "Service Unavailable: the upstream service failed to respond\n")
+				})
+			})
+		})
+
+		Convey("And the fallback to Babbage is enabled", func() {
+			cfg.CircuitBreaker.BabbageFallback = true
+			legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+			So(err, ShouldBeNil)
+			So(sendDatasetRequest(legacyCacheProxy).Code, ShouldEqual, http.StatusServiceUnavailable)
+
+			Convey("When a dataset landing page is requested", func() {
+				w := sendDatasetRequest(legacyCacheProxy)
+
+				Convey("Then the proxy response should match the Babbage response", func() {
+					So(w.Code, ShouldEqual, http.StatusOK)
+					So(w.Body.String(), ShouldEqual, "Mock Babbage Response")
+				})
+			})
+		})
+	})
+}
+
 func TestProxyHandleRoutingSearch(t *testing.T) {
 	Convey("Given a Proxy and an enabled Search Controller", t, func() {
 		mockSearchServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -132,7 +313,8 @@ func TestProxyHandleRoutingSearch(t *testing.T) {
 		router := mux.NewRouter()
 		cfg := &config.Config{SearchControllerURL: mockSearchServer.URL, EnableSearchController: true}
 
-		legacyCacheProxy := Setup(ctx, router, cfg)
+		legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
 
 		Convey("When a search request is sent", func() {
 			w := httptest.NewRecorder()
@@ -173,7 +355,8 @@ func TestProxyHandleRoutingSearch(t *testing.T) {
 		router := mux.NewRouter()
 		cfg := &config.Config{BabbageURL: mockBabbageServer.URL, EnableSearchController: false}
 
-		legacyCacheProxy := Setup(ctx, router, cfg)
+		legacyCacheProxy, err := Setup(ctx, router, cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
 
 		Convey("When a search request is sent", func() {
 			w := httptest.NewRecorder()
@@ -200,94 +383,3 @@ func TestProxyHandleRoutingSearch(t *testing.T) {
 		})
 	})
 }
-func TestIsReleaseCalendarURL(t *testing.T) {
-	Convey("Given a list of release calendar URLs", t, func() {
-		Convey("When the 'IsReleaseCalendarURL' function is called", func() {
-			for _, url := range releaseCalendarURLs {
-				isReleaseCalendar := IsReleaseCalendarURL(url)
-
-				Convey("Then it should evaluate to true for "+url, func() {
-					So(isReleaseCalendar, ShouldBeTrue)
-				})
-			}
-		})
-	})
-	Convey("Given a list of babbage URLs", t, func() {
-		Convey("When the 'IsReleaseCalendarURL' function is called", func() {
-			for _, url := range babbageURLs {
-				isReleaseCalendar := IsReleaseCalendarURL(url)
-
-				Convey("Then it should evaluate to false for "+url, func() {
-					So(isReleaseCalendar, ShouldBeFalse)
-				})
-			}
-		})
-	})
-}
-
-func TestIsSearchControllerURL(t *testing.T) {
-	Convey("Given a list of search controller URLs", t, func() {
-		Convey("When the 'IsSearchControllerURL' function is called", func() {
-			for _, url := range searchControllerURLs {
-				isSearchController := IsSearchControllerURL(url)
-
-				Convey("Then it should evaluate to true for "+url, func() {
-					So(isSearchController, ShouldBeTrue)
-				})
-			}
-		})
-	})
-	Convey("Given a list of babbage URLs", t, func() {
-		Convey("When the 'IsSearchControllerURL' function is called", func() {
-			for _, url := range babbageURLs {
-				isSearchController := IsSearchControllerURL(url)
-
-				Convey("Then it should evaluate to false for "+url, func() {
-					So(isSearchController, ShouldBeFalse)
-				})
-			}
-		})
-	})
-
-	Convey("Given a list of release calendar URLs", t, func() {
-		Convey("When the 'IsSearchControllerURL' function is called", func() {
-			for _, url := range releaseCalendarURLs {
-				isSearchController := IsSearchControllerURL(url)
-
-				Convey("Then it should evaluate to false for "+url, func() {
-					So(isSearchController, ShouldBeFalse)
-				})
-			}
-		})
-	})
-}
-
-func TestIsDatasetLandingPage(t *testing.T) {
-	Convey("Given a page type of dataset_landing_page", t, func() {
-		Convey("When the 'IsDatasetLandingPage' function is called with the page type", func() {
-			isDatasetLandingPage := IsDatasetLandingPage("dataset_landing_page")
-
-			Convey("Then it should return true", func() {
-				So(isDatasetLandingPage, ShouldBeTrue)
-			})
-		})
-	})
-	Convey("Given a page type of bulletin", t, func() {
-		Convey("When the 'IsDatasetLandingPage' function is called with the page type", func() {
-			isDatasetLandingPage := IsDatasetLandingPage("bulletin")
-
-			Convey("Then it should return false", func() {
-				So(isDatasetLandingPage, ShouldBeFalse)
-			})
-		})
-	})
-	Convey("Given a blank content type", t, func() {
-		Convey("When the 'IsDatasetLandingPage' function is called", func() {
-			isDatasetLandingPage := IsDatasetLandingPage("")
-
-			Convey("Then it should return false", func() {
-				So(isDatasetLandingPage, ShouldBeFalse)
-			})
-		})
-	})
-}
@@ -4,23 +4,87 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/ONSdigital/dp-legacy-cache-proxy/circuitbreaker"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
 	"github.com/gorilla/mux"
 )
 
 // Proxy provides a struct to wrap the proxy around
 type Proxy struct {
-	Router *mux.Router
+	Router          *mux.Router
+	RoutingTable    *RoutingTable
+	clients         map[string]*http.Client
+	circuitBreakers map[string]*circuitbreaker.CircuitBreaker
+	retryBudget     *retryBudget
+	releaseTimes    *response.ReleaseTimeCache
+	cachePolicy     *response.CachePolicy
+	clock           *response.Clock
 }
 
-// Setup function sets up the proxy and returns a Proxy
-func Setup(_ context.Context, r *mux.Router, cfg *config.Config) *Proxy {
+// Setup function sets up the proxy and returns a Proxy, which caches the release times from the given source. An error
+// is returned if the routing table, the cache policy or the clock is not valid.
+func Setup(ctx context.Context, r *mux.Router, cfg *config.Config, releaseTimeSource response.ReleaseTimeSource) (*Proxy, error) {
+	routingTable, err := LoadRoutingTable(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cachePolicy, err := response.LoadCachePolicy(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	clock, err := response.NewClock(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	circuitBreakers := make(map[string]*circuitbreaker.CircuitBreaker)
+	for _, upstream := range routingTable.Upstreams() {
+		circuitBreakerCfg := cfg.CircuitBreaker.CircuitBreaker
+		if upstream == UpstreamBabbage {
+			// Failing fast would take down every page that Babbage serves, as nothing can be served in its place
+			circuitBreakerCfg.Enabled = circuitBreakerCfg.Enabled && cfg.CircuitBreaker.BabbageEnabled
+		}
+		circuitBreakers[upstream] = circuitbreaker.New(upstream, circuitBreakerCfg)
+	}
+
 	proxy := &Proxy{
-		Router: r,
+		Router:          r,
+		RoutingTable:    routingTable,
+		clients:         newUpstreamClients(routingTable, cfg),
+		circuitBreakers: circuitBreakers,
+		retryBudget:     newRetryBudget(cfg.UpstreamRetryBudgetRatio),
+		releaseTimes:    response.NewReleaseTimeCache(cfg, releaseTimeSource),
+		cachePolicy:     cachePolicy,
+		clock:           clock,
 	}
 
 	r.PathPrefix("/").Name("Proxy Catch-All").HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		proxy.manage(req.Context(), w, req, cfg)
 	})
-	return proxy
+	return proxy, nil
+}
+
+// Close closes any idle connections to the upstream services
+func (proxy *Proxy) Close() {
+	for _, client := range proxy.clients {
+		client.CloseIdleConnections()
+	}
+}
+
+// CircuitBreakers returns the circuit breaker of every upstream, keyed by the upstream's name
+func (proxy *Proxy) CircuitBreakers() map[string]*circuitbreaker.CircuitBreaker {
+	return proxy.circuitBreakers
+}
+
+// ReleaseTimeCache returns the cache of the release times returned by the release time source
+func (proxy *Proxy) ReleaseTimeCache() *response.ReleaseTimeCache {
+	return proxy.releaseTimes
+}
+
+// Clock returns the clock that the cache headers are calculated from
+func (proxy *Proxy) Clock() *response.Clock {
+	return proxy.clock
 }
@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	responsemock "github.com/ONSdigital/dp-legacy-cache-proxy/response/mock"
 	"github.com/gorilla/mux"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -16,7 +18,8 @@ func TestSetup(t *testing.T) {
 		ctx := context.Background()
 		r := mux.NewRouter()
 		cfg := &config.Config{}
-		legacyCacheProxy := Setup(ctx, r, cfg)
+		legacyCacheProxy, err := Setup(ctx, r, cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
 
 		Convey("When created, all HTTP methods should be accepted", func() {
 			So(hasRoute(legacyCacheProxy.Router, "/", http.MethodGet), ShouldBeTrue)
@@ -37,3 +40,12 @@ func hasRoute(r *mux.Router, path, method string) bool {
 	match := &mux.RouteMatch{}
 	return r.Match(req, match)
 }
+
+// newNotFoundReleaseTimeSource returns a release time source that does not know any page
+func newNotFoundReleaseTimeSource() *responsemock.ReleaseTimeSourceMock {
+	return &responsemock.ReleaseTimeSourceMock{
+		GetReleaseTimeFunc: func(_ context.Context, _ string) (time.Time, int, error) {
+			return time.Time{}, http.StatusNotFound, nil
+		},
+	}
+}
@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+const (
+	maxRetryBackoff = 2 * time.Second
+
+	// retryBudgetReserve is the number of retries that are always available, so that a quiet proxy can still retry
+	retryBudgetReserve = 10.0
+	// retryBudgetCap is the maximum number of retries that can be saved up during periods with no failures
+	retryBudgetCap = 100.0
+)
+
+// retryBudget limits the number of retries to a ratio of the number of requests, so that a failing upstream does not
+// receive a retry storm. Each request deposits the ratio into the budget and each retry withdraws one from it.
+type retryBudget struct {
+	mutex   sync.Mutex
+	ratio   float64
+	balance float64
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	return &retryBudget{
+		ratio:   ratio,
+		balance: retryBudgetReserve,
+	}
+}
+
+func (b *retryBudget) deposit() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.balance = min(b.balance+b.ratio, retryBudgetCap)
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.balance < 1 {
+		return false
+	}
+
+	b.balance--
+	return true
+}
+
+// do sends the request to the upstream. GET and HEAD requests without a body are retried, with exponential backoff and
+// jitter, when the connection to the upstream fails before any response is received.
+func (proxy *Proxy) do(ctx context.Context, upstreamName string, proxyReq *http.Request, cfg *config.Config) (*http.Response, error) {
+	client := proxy.clients[upstreamName]
+
+	maxAttempts := 1
+	if isRetryableRequest(proxyReq) {
+		maxAttempts = max(cfg.UpstreamMaxAttempts, 1)
+		proxy.retryBudget.deposit()
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := sendAttempt(ctx, client, proxyReq, cfg.UpstreamAttemptTimeout)
+		if err == nil || attempt >= maxAttempts || !isRetryableError(err) {
+			return resp, err
+		}
+
+		logData := log.Data{"upstream": upstreamName, "attempt": attempt, "error": err.Error()}
+		if !proxy.retryBudget.withdraw() {
+			log.Warn(ctx, "retry budget exhausted, not retrying upstream request", logData)
+			return nil, err
+		}
+
+		backoff := retryBackoff(cfg.UpstreamRetryBackoff, attempt)
+		logData["backoff"] = backoff.String()
+		log.Warn(ctx, "retrying upstream request", logData)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+// sendAttempt sends a single attempt of the request, limited to the attempt timeout if there is one. The attempt's
+// context is only cancelled once the response body has been closed.
+func sendAttempt(ctx context.Context, client *http.Client, proxyReq *http.Request, attemptTimeout time.Duration) (*http.Response, error) {
+	if attemptTimeout <= 0 {
+		return client.Do(proxyReq.Clone(ctx)) //nolint:gosec // we control the URLs so not technically as tainted as it suggests
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	resp, err := client.Do(proxyReq.Clone(attemptCtx)) //nolint:gosec // we control the URLs so not technically as tainted as it suggests
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func isRetryableRequest(req *http.Request) bool {
+	return (req.Method == http.MethodGet || req.Method == http.MethodHead) && req.ContentLength == 0
+}
+
+// isRetryableError determines if the error happened before any response was received from the upstream. Timeouts are
+// not retried, as the upstream may still be processing the request.
+func isRetryableError(err error) bool {
+	return !isTimeout(err) && isConnectionFailure(err)
+}
+
+// retryBackoff returns a random duration (full jitter) of up to the base backoff doubled for every previous attempt
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	ceiling := base
+	for i := 1; i < attempt && ceiling < maxRetryBackoff; i++ {
+		ceiling *= 2
+	}
+
+	return rand.N(min(ceiling, maxRetryBackoff)) + 1 //nolint:gosec // the jitter does not need a cryptographically secure random number
+}
@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newFlakyServer returns a server that drops the connection, without sending a response, for the first failures requests
+func newFlakyServer(failures int32, attempts *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(attempts, 1) <= failures {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				panic(err)
+			}
+			_ = conn.Close()
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("Mock Babbage Response"))
+	}))
+}
+
+func TestProxyRetries(t *testing.T) {
+	Convey("Given a Proxy and a Babbage server that drops the first two connections", t, func() {
+		var attempts int32
+		flakyBabbageServer := newFlakyServer(2, &attempts)
+		defer flakyBabbageServer.Close()
+
+		cfg := &config.Config{
+			BabbageURL:               flakyBabbageServer.URL,
+			UpstreamMaxAttempts:      3,
+			UpstreamRetryBackoff:     time.Millisecond,
+			UpstreamRetryBudgetRatio: 0.1,
+		}
+
+		Convey("When a GET request is sent", func() {
+			legacyCacheProxy, err := Setup(context.Background(), mux.NewRouter(), cfg, newNotFoundReleaseTimeSource())
+			So(err, ShouldBeNil)
+			w := httptest.NewRecorder()
+			legacyCacheProxy.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test-endpoint", http.NoBody))
+
+			Convey("Then the request is retried until it succeeds", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, "Mock Babbage Response")
+				So(atomic.LoadInt32(&attempts), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When a GET request is sent and only two attempts are allowed", func() {
+			cfg.UpstreamMaxAttempts = 2
+			legacyCacheProxy, err := Setup(context.Background(), mux.NewRouter(), cfg, newNotFoundReleaseTimeSource())
+			So(err, ShouldBeNil)
+			w := httptest.NewRecorder()
+			legacyCacheProxy.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test-endpoint", http.NoBody))
+
+			Convey("Then the request fails after two attempts", func() {
+				So(w.Code, ShouldEqual, http.StatusBadGateway)
+				So(atomic.LoadInt32(&attempts), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When a POST request is sent", func() {
+			legacyCacheProxy, err := Setup(context.Background(), mux.NewRouter(), cfg, newNotFoundReleaseTimeSource())
+			So(err, ShouldBeNil)
+			w := httptest.NewRecorder()
+			legacyCacheProxy.Router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/test-endpoint", strings.NewReader("body")))
+
+			Convey("Then the request is not retried", func() {
+				So(w.Code, ShouldEqual, http.StatusBadGateway)
+				So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When a GET request is sent and the retry budget has been used up", func() {
+			legacyCacheProxy, err := Setup(context.Background(), mux.NewRouter(), cfg, newNotFoundReleaseTimeSource())
+			So(err, ShouldBeNil)
+			legacyCacheProxy.retryBudget.balance = 0
+			w := httptest.NewRecorder()
+			legacyCacheProxy.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test-endpoint", http.NoBody))
+
+			Convey("Then the request is not retried", func() {
+				So(w.Code, ShouldEqual, http.StatusBadGateway)
+				So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a Proxy with a per-attempt timeout and a Babbage server that is too slow", t, func() {
+		var attempts int32
+		slowBabbageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer slowBabbageServer.Close()
+
+		cfg := &config.Config{
+			BabbageURL:               slowBabbageServer.URL,
+			UpstreamMaxAttempts:      3,
+			UpstreamAttemptTimeout:   50 * time.Millisecond,
+			UpstreamRetryBudgetRatio: 0.1,
+		}
+		legacyCacheProxy, err := Setup(context.Background(), mux.NewRouter(), cfg, newNotFoundReleaseTimeSource())
+		So(err, ShouldBeNil)
+
+		Convey("When a GET request is sent", func() {
+			w := httptest.NewRecorder()
+			legacyCacheProxy.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test-endpoint", http.NoBody))
+
+			Convey("Then the attempt times out and is not retried", func() {
+				So(w.Code, ShouldEqual, http.StatusGatewayTimeout)
+				So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestRetryBudget(t *testing.T) {
+	Convey("Given a retry budget with a ratio of 0.5 and no balance", t, func() {
+		budget := newRetryBudget(0.5)
+		budget.balance = 0
+
+		Convey("When a single request is deposited", func() {
+			budget.deposit()
+
+			Convey("Then a retry cannot be withdrawn", func() {
+				So(budget.withdraw(), ShouldBeFalse)
+			})
+		})
+
+		Convey("When two requests are deposited", func() {
+			budget.deposit()
+			budget.deposit()
+
+			Convey("Then a single retry can be withdrawn", func() {
+				So(budget.withdraw(), ShouldBeTrue)
+				So(budget.withdraw(), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	Convey("Given a base backoff of 100ms", t, func() {
+		base := 100 * time.Millisecond
+
+		Convey("When the backoff is calculated for a series of attempts", func() {
+			Convey("Then it should never exceed the exponential ceiling", func() {
+				for i := 0; i < 50; i++ {
+					So(retryBackoff(base, 1), ShouldBeLessThanOrEqualTo, base)
+					So(retryBackoff(base, 3), ShouldBeLessThanOrEqualTo, 4*base)
+					So(retryBackoff(base, 100), ShouldBeLessThanOrEqualTo, maxRetryBackoff)
+				}
+			})
+		})
+
+		Convey("When there is no base backoff", func() {
+			Convey("Then there should be no backoff", func() {
+				So(retryBackoff(0, 3), ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/pkg/errors"
+)
+
+// Names of the upstream services that a route can forward requests to
+const (
+	UpstreamBabbage           = "babbage"
+	UpstreamReleaseCalendar   = "release-calendar"
+	UpstreamSearchController  = "search-controller"
+	UpstreamDatasetController = "dataset-controller"
+)
+
+// Route is a single rule of the routing table. Every match field that is set must match the request for the route to
+// apply. Upstream is either the name of one of the configured upstream services or an absolute URL. BabbageFallback
+// marks routes for pages that Babbage can still serve, in case the upstream is unavailable.
+type Route struct {
+	Name            string   `json:"name"`
+	PathPrefix      string   `json:"path_prefix,omitempty"`
+	PathSuffix      string   `json:"path_suffix,omitempty"`
+	PathRegex       string   `json:"path_regex,omitempty"`
+	PageType        string   `json:"page_type,omitempty"`
+	Methods         []string `json:"methods,omitempty"`
+	Upstream        string   `json:"upstream"`
+	BabbageFallback bool     `json:"babbage_fallback,omitempty"`
+}
+
+// Target is the upstream that a request has been routed to
+type Target struct {
+	Upstream        string
+	URL             string
+	BabbageFallback bool
+}
+
+// RoutingTableFile represents the contents of the file pointed at by ROUTING_TABLE_FILE
+type RoutingTableFile struct {
+	Routes []Route `json:"routes"`
+}
+
+// RoutingTable holds the validated, ordered routes used to choose an upstream for each request
+type RoutingTable struct {
+	routes    []compiledRoute
+	upstreams map[string]string
+}
+
+type compiledRoute struct {
+	Route
+	pathRegexp *regexp.Regexp
+}
+
+// DefaultRoutes returns the routing table used when no ROUTING_TABLE_FILE is configured
+func DefaultRoutes() []Route {
+	return []Route{
+		{Name: "release calendar", PathPrefix: "/releases/", Upstream: UpstreamReleaseCalendar},
+		{Name: "search controller", PathRegex: `/(previousreleases|relatedData|relateddata)$`, Upstream: UpstreamSearchController, BabbageFallback: true},
+		{Name: "dataset landing page", PageType: "dataset_landing_page", Upstream: UpstreamDatasetController, BabbageFallback: true},
+	}
+}
+
+// LoadRoutingTable builds the routing table from the file in the configuration, falling back to the default routes
+func LoadRoutingTable(cfg *config.Config) (*RoutingTable, error) {
+	routes := DefaultRoutes()
+
+	if cfg.RoutingTableFile != "" {
+		contents, err := os.ReadFile(cfg.RoutingTableFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read the routing table file")
+		}
+
+		var file RoutingTableFile
+		if err = json.Unmarshal(contents, &file); err != nil {
+			return nil, errors.Wrap(err, "unable to parse the routing table file")
+		}
+
+		routes = file.Routes
+	}
+
+	return NewRoutingTable(routes, cfg)
+}
+
+// NewRoutingTable validates the given routes and returns a RoutingTable. Routes targeting the Search Controller are
+// dropped unless it has been enabled.
+func NewRoutingTable(routes []Route, cfg *config.Config) (*RoutingTable, error) {
+	table := &RoutingTable{
+		upstreams: map[string]string{
+			UpstreamBabbage:           cfg.BabbageURL,
+			UpstreamReleaseCalendar:   cfg.RelCalURL,
+			UpstreamSearchController:  cfg.SearchControllerURL,
+			UpstreamDatasetController: cfg.DatasetControllerURL,
+		},
+	}
+
+	for i, route := range routes {
+		compiled, err := table.compile(route)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %d (%q): %w", i, route.Name, err)
+		}
+
+		if isCatchAll(route) && i < len(routes)-1 {
+			return nil, fmt.Errorf("invalid route %d (%q): a route without any match fields must be the last one", i, route.Name)
+		}
+
+		if route.Upstream == UpstreamSearchController && !cfg.EnableSearchController {
+			continue
+		}
+
+		table.routes = append(table.routes, compiled)
+	}
+
+	return table, nil
+}
+
+func (t *RoutingTable) compile(route Route) (compiledRoute, error) {
+	compiled := compiledRoute{Route: route}
+
+	if route.Upstream == "" {
+		return compiled, errors.New("an upstream is required")
+	}
+
+	if _, isNamedUpstream := t.upstreams[route.Upstream]; !isNamedUpstream {
+		upstreamURL, err := url.Parse(route.Upstream)
+		if err != nil || !upstreamURL.IsAbs() {
+			return compiled, fmt.Errorf("upstream %q is neither a known upstream nor an absolute URL", route.Upstream)
+		}
+	}
+
+	if route.PathRegex != "" {
+		pathRegexp, err := regexp.Compile(route.PathRegex)
+		if err != nil {
+			return compiled, errors.Wrap(err, "invalid path regex")
+		}
+		compiled.pathRegexp = pathRegexp
+	}
+
+	for _, method := range route.Methods {
+		if !config.IsHTTPMethod(method) {
+			return compiled, fmt.Errorf("invalid method %q", method)
+		}
+	}
+
+	return compiled, nil
+}
+
+// Match returns the upstream that the request should be forwarded to. Requests that do not match any route are
+// forwarded to Babbage.
+func (t *RoutingTable) Match(req *http.Request) Target {
+	path := req.URL.EscapedPath()
+	pageType := req.Header.Get("Ons-Page-Type")
+
+	for i := range t.routes {
+		if t.routes[i].matches(path, pageType, req.Method) {
+			target := t.Resolve(t.routes[i].Upstream)
+			target.BabbageFallback = t.routes[i].BabbageFallback && target.Upstream != UpstreamBabbage
+			return target
+		}
+	}
+
+	return t.Resolve(UpstreamBabbage)
+}
+
+// Upstreams returns the names of all the upstreams that requests can be forwarded to, including Babbage
+func (t *RoutingTable) Upstreams() []string {
+	upstreams := []string{UpstreamBabbage}
+
+	for i := range t.routes {
+		if !slices.Contains(upstreams, t.routes[i].Upstream) {
+			upstreams = append(upstreams, t.routes[i].Upstream)
+		}
+	}
+
+	return upstreams
+}
+
+// Resolve returns the target for the given upstream name or URL
+func (t *RoutingTable) Resolve(upstream string) Target {
+	if namedURL, isNamedUpstream := t.upstreams[upstream]; isNamedUpstream {
+		return Target{Upstream: upstream, URL: namedURL}
+	}
+
+	return Target{Upstream: upstream, URL: strings.TrimSuffix(upstream, "/")}
+}
+
+func (r *compiledRoute) matches(path, pageType, method string) bool {
+	if r.PathPrefix != "" && !strings.HasPrefix(path, r.PathPrefix) {
+		return false
+	}
+
+	if r.PathSuffix != "" && !strings.HasSuffix(path, r.PathSuffix) {
+		return false
+	}
+
+	if r.pathRegexp != nil && !r.pathRegexp.MatchString(path) {
+		return false
+	}
+
+	if r.PageType != "" && r.PageType != pageType {
+		return false
+	}
+
+	if len(r.Methods) > 0 && !config.ContainsFold(r.Methods, method) {
+		return false
+	}
+
+	return true
+}
+
+func isCatchAll(route Route) bool {
+	return route.PathPrefix == "" && route.PathSuffix == "" && route.PathRegex == "" && route.PageType == "" && len(route.Methods) == 0
+}
@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	releaseCalendarURLs = []string{
+		"/releases/greenjobscurrentandupcomingworkmarch2022",
+		"/releases/post2019westminsterparliamentaryconstituenciesandseneddelectoralregionsdataenglandandwalescensus2021",
+		"/releases/mycollectionpage1",
+		"/releases/timespentinnature",
+		"/releases/constructionstatisticsgreatbritain2022",
+	}
+
+	babbageURLs = []string{
+		"/visualisations/dvc1945/seasonalflu/index.html",
+		"/generator?uri=/economy/economicoutputandproductivity/output/bulletins/economicactivityandsocialchangeintheukrealtimeindicators/15february2024/426e63a0&format=csv",
+		"/economy/inflationandpriceindices/bulletins/producerpriceinflation/latest",
+		"/economy/grossdomesticproductgdp/timeseries/abmi/pn2/linechartconfig",
+		"/businessindustryandtrade/changestobusiness/mergersandacquisitions/datasets/timeseries/15march2024",
+	}
+
+	searchControllerURLs = []string{
+		"/economy/previousreleases",
+		"/businessindustryandtrade/changestobusiness/mergersandacquisitions/relatedData",
+		"/economy/economicoutputandproductivity/output/bulletins/economicactivityandsocialchangeintheukrealtimeindicators/relateddata",
+	}
+)
+
+func TestDefaultRoutingTable(t *testing.T) {
+	Convey("Given the default routing table with the Search Controller enabled", t, func() {
+		cfg := &config.Config{
+			BabbageURL:             "http://babbage",
+			RelCalURL:              "http://release-calendar",
+			SearchControllerURL:    "http://search-controller",
+			DatasetControllerURL:   "http://dataset-controller",
+			EnableSearchController: true,
+		}
+		table, err := LoadRoutingTable(cfg)
+		So(err, ShouldBeNil)
+
+		testCases := []struct {
+			urls     []string
+			upstream string
+		}{
+			{urls: releaseCalendarURLs, upstream: UpstreamReleaseCalendar},
+			{urls: searchControllerURLs, upstream: UpstreamSearchController},
+			{urls: babbageURLs, upstream: UpstreamBabbage},
+		}
+
+		Convey("When a request is matched against the table", func() {
+			for _, tc := range testCases {
+				for _, url := range tc.urls {
+					target := table.Match(httptest.NewRequest(http.MethodGet, url, http.NoBody))
+
+					Convey("Then it should be routed to "+tc.upstream+" for "+url, func() {
+						So(target.Upstream, ShouldEqual, tc.upstream)
+						So(target.URL, ShouldEqual, "http://"+tc.upstream)
+					})
+				}
+			}
+		})
+
+		Convey("When a request with a page type of dataset_landing_page is matched against the table", func() {
+			req := httptest.NewRequest(http.MethodGet, "/some-taxonomy/datasets/some-dataset", http.NoBody)
+			req.Header.Set("Ons-Page-Type", "dataset_landing_page")
+			target := table.Match(req)
+
+			Convey("Then it should be routed to the Dataset Controller, with Babbage as a fallback", func() {
+				So(target.Upstream, ShouldEqual, UpstreamDatasetController)
+				So(target.BabbageFallback, ShouldBeTrue)
+			})
+		})
+
+		Convey("When a request with a page type of bulletin is matched against the table", func() {
+			req := httptest.NewRequest(http.MethodGet, "/some-taxonomy/bulletins/some-bulletin", http.NoBody)
+			req.Header.Set("Ons-Page-Type", "bulletin")
+			target := table.Match(req)
+
+			Convey("Then it should be routed to Babbage", func() {
+				So(target.Upstream, ShouldEqual, UpstreamBabbage)
+				So(target.BabbageFallback, ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given the default routing table with the Search Controller disabled", t, func() {
+		cfg := &config.Config{EnableSearchController: false}
+		table, err := LoadRoutingTable(cfg)
+		So(err, ShouldBeNil)
+
+		Convey("When a search controller request is matched against the table", func() {
+			for _, url := range searchControllerURLs {
+				target := table.Match(httptest.NewRequest(http.MethodGet, url, http.NoBody))
+
+				Convey("Then it should be routed to Babbage for "+url, func() {
+					So(target.Upstream, ShouldEqual, UpstreamBabbage)
+				})
+			}
+		})
+	})
+}
+
+func TestRoutingTableMatch(t *testing.T) {
+	Convey("Given a routing table with every kind of match field", t, func() {
+		routes := []Route{
+			{Name: "methods", PathPrefix: "/submit/", Methods: []string{"post"}, Upstream: "http://submissions/"},
+			{Name: "suffix", PathSuffix: "/linechartconfig", Upstream: UpstreamSearchController},
+			{Name: "catch-all", Upstream: UpstreamDatasetController},
+		}
+		table, err := NewRoutingTable(routes, &config.Config{EnableSearchController: true})
+		So(err, ShouldBeNil)
+
+		Convey("When a request matches every field of a route with an absolute URL upstream", func() {
+			target := table.Match(httptest.NewRequest(http.MethodPost, "/submit/form", http.NoBody))
+
+			Convey("Then it should be routed to that URL", func() {
+				So(target.Upstream, ShouldEqual, "http://submissions/")
+				So(target.URL, ShouldEqual, "http://submissions")
+			})
+		})
+
+		Convey("When a request only matches some fields of a route", func() {
+			target := table.Match(httptest.NewRequest(http.MethodGet, "/submit/form", http.NoBody))
+
+			Convey("Then it should be routed by the next matching route", func() {
+				So(target.Upstream, ShouldEqual, UpstreamDatasetController)
+			})
+		})
+
+		Convey("When a request matches a path suffix", func() {
+			target := table.Match(httptest.NewRequest(http.MethodGet, "/timeseries/abmi/linechartconfig", http.NoBody))
+
+			Convey("Then it should be routed by that route", func() {
+				So(target.Upstream, ShouldEqual, UpstreamSearchController)
+			})
+		})
+	})
+}
+
+func TestNewRoutingTableValidation(t *testing.T) {
+	Convey("Given a series of invalid routes", t, func() {
+		testCases := map[string][]Route{
+			"missing upstream":    {{Name: "a", PathPrefix: "/a"}},
+			"unknown upstream":    {{Name: "a", PathPrefix: "/a", Upstream: "not-a-service"}},
+			"invalid regex":       {{Name: "a", PathRegex: "(", Upstream: UpstreamBabbage}},
+			"invalid method":      {{Name: "a", PathPrefix: "/a", Methods: []string{"FETCH"}, Upstream: UpstreamBabbage}},
+			"unreachable route":   {{Name: "a", Upstream: UpstreamBabbage}, {Name: "b", PathPrefix: "/b", Upstream: UpstreamBabbage}},
+			"invalid catch-all":   {{Name: "a", Upstream: "relative/path"}},
+			"second invalid rule": {{Name: "a", PathPrefix: "/a", Upstream: UpstreamBabbage}, {Name: "b", PathRegex: "[", Upstream: UpstreamBabbage}},
+		}
+
+		for description, routes := range testCases {
+			Convey("When a routing table is created with a route with a "+description, func() {
+				table, err := NewRoutingTable(routes, &config.Config{})
+
+				Convey("Then an error should be returned", func() {
+					So(err, ShouldNotBeNil)
+					So(table, ShouldBeNil)
+				})
+			})
+		}
+	})
+}
+
+func TestLoadRoutingTableFromFile(t *testing.T) {
+	Convey("Given a routing table file", t, func() {
+		routingTableFile := filepath.Join(t.TempDir(), "routes.json")
+		cfg := &config.Config{RoutingTableFile: routingTableFile, BabbageURL: "http://babbage"}
+
+		Convey("When the file contains a valid routing table", func() {
+			err := os.WriteFile(routingTableFile, []byte(`{"routes": [{"name": "legacy", "path_prefix": "/ons/", "upstream": "babbage"}]}`), 0o600)
+			So(err, ShouldBeNil)
+			table, err := LoadRoutingTable(cfg)
+
+			Convey("Then the routes in the file are used", func() {
+				So(err, ShouldBeNil)
+				So(table.routes, ShouldHaveLength, 1)
+				So(table.routes[0].Name, ShouldEqual, "legacy")
+			})
+		})
+
+		Convey("When the file is not valid JSON", func() {
+			err := os.WriteFile(routingTableFile, []byte(`routes:`), 0o600)
+			So(err, ShouldBeNil)
+			_, err = LoadRoutingTable(cfg)
+
+			Convey("Then an error should be returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When the file does not exist", func() {
+			cfg.RoutingTableFile = filepath.Join(t.TempDir(), "missing.json")
+			_, err := LoadRoutingTable(cfg)
+
+			Convey("Then an error should be returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
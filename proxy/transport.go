@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+)
+
+const keepAliveInterval = 30 * time.Second
+
+// newUpstreamClient creates a long-lived HTTP client, with its own connection pool, for a single upstream service.
+// Redirects are never followed, so that they are passed back to the caller unchanged.
+func newUpstreamClient(transportCfg config.UpstreamTransport) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   transportCfg.DialTimeout,
+		KeepAlive: keepAliveInterval,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   transportCfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: transportCfg.ResponseHeaderTimeout,
+		MaxIdleConns:          transportCfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   transportCfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       transportCfg.IdleConnTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   transportCfg.Timeout,
+		// nolint:revive // param names give context here.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// newUpstreamClients creates one client per upstream in the routing table. Upstreams given as an absolute URL in the
+// routing table use the same transport configuration as Babbage.
+func newUpstreamClients(routingTable *RoutingTable, cfg *config.Config) map[string]*http.Client {
+	transportConfigs := map[string]config.UpstreamTransport{
+		UpstreamBabbage:           cfg.BabbageTransport,
+		UpstreamReleaseCalendar:   cfg.RelCalTransport,
+		UpstreamSearchController:  cfg.SearchControllerTransport,
+		UpstreamDatasetController: cfg.DatasetControllerTransport,
+	}
+
+	clients := make(map[string]*http.Client)
+	for _, upstream := range routingTable.Upstreams() {
+		transportCfg, isNamedUpstream := transportConfigs[upstream]
+		if !isNamedUpstream {
+			transportCfg = cfg.BabbageTransport
+		}
+		clients[upstream] = newUpstreamClient(transportCfg)
+	}
+
+	return clients
+}
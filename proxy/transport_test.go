@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewUpstreamClient(t *testing.T) {
+	Convey("Given an upstream transport configuration", t, func() {
+		transportCfg := config.UpstreamTransport{
+			DialTimeout:           time.Second,
+			TLSHandshakeTimeout:   2 * time.Second,
+			ResponseHeaderTimeout: 50 * time.Millisecond,
+			Timeout:               4 * time.Second,
+			MaxIdleConns:          10,
+			MaxIdleConnsPerHost:   5,
+			IdleConnTimeout:       6 * time.Second,
+		}
+
+		Convey("When a client is created", func() {
+			client := newUpstreamClient(transportCfg)
+			transport, isHTTPTransport := client.Transport.(*http.Transport)
+
+			Convey("Then it should use the configured timeouts and pool sizes", func() {
+				So(isHTTPTransport, ShouldBeTrue)
+				So(client.Timeout, ShouldEqual, 4*time.Second)
+				So(transport.TLSHandshakeTimeout, ShouldEqual, 2*time.Second)
+				So(transport.ResponseHeaderTimeout, ShouldEqual, 50*time.Millisecond)
+				So(transport.MaxIdleConns, ShouldEqual, 10)
+				So(transport.MaxIdleConnsPerHost, ShouldEqual, 5)
+				So(transport.IdleConnTimeout, ShouldEqual, 6*time.Second)
+			})
+		})
+
+		Convey("When the client sends a request to an upstream that is too slow to respond", func() {
+			slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer slowServer.Close()
+
+			client := newUpstreamClient(transportCfg)
+			resp, err := client.Get(slowServer.URL)
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+
+			Convey("Then the request should time out", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When the client has no overall timeout and the upstream takes a while to send a large body", func() {
+			transportCfg.Timeout = 0
+			slowBodyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				time.Sleep(200 * time.Millisecond)
+				_, _ = w.Write([]byte("rest of the download"))
+			}))
+			defer slowBodyServer.Close()
+
+			client := newUpstreamClient(transportCfg)
+			resp, err := client.Get(slowBodyServer.URL)
+			So(err, ShouldBeNil)
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			Convey("Then the whole body should be read, even after the response header timeout", func() {
+				So(err, ShouldBeNil)
+				So(string(body), ShouldEqual, "rest of the download")
+			})
+		})
+	})
+}
+
+func TestNewUpstreamClients(t *testing.T) {
+	Convey("Given a routing table with named and absolute URL upstreams", t, func() {
+		cfg := &config.Config{
+			BabbageTransport:          config.UpstreamTransport{Timeout: time.Second},
+			RelCalTransport:           config.UpstreamTransport{Timeout: 2 * time.Second},
+			SearchControllerTransport: config.UpstreamTransport{Timeout: 3 * time.Second},
+		}
+		routes := []Route{
+			{Name: "release calendar", PathPrefix: "/releases/", Upstream: UpstreamReleaseCalendar},
+			{Name: "search", PathSuffix: "/relateddata", Upstream: UpstreamSearchController},
+			{Name: "other", PathPrefix: "/other/", Upstream: "http://other-service"},
+		}
+		routingTable, err := NewRoutingTable(routes, cfg)
+		So(err, ShouldBeNil)
+
+		Convey("When the upstream clients are created", func() {
+			clients := newUpstreamClients(routingTable, cfg)
+
+			Convey("Then there should be one client for every reachable upstream, with its own configuration", func() {
+				So(clients, ShouldHaveLength, 3)
+				So(clients[UpstreamBabbage].Timeout, ShouldEqual, time.Second)
+				So(clients[UpstreamReleaseCalendar].Timeout, ShouldEqual, 2*time.Second)
+				So(clients["http://other-service"].Timeout, ShouldEqual, time.Second)
+				So(clients[UpstreamSearchController], ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// upstreamErrorStatusCode returns the status code the proxy should respond with when a request to an upstream fails.
+// Timeouts result in a 504 Gateway Timeout and failures to connect to (or get a response from) the upstream result in a
+// 502 Bad Gateway. Any other error is considered to be the proxy's own fault and results in a 500 Internal Server Error.
+func upstreamErrorStatusCode(err error) int {
+	if isTimeout(err) {
+		return http.StatusGatewayTimeout
+	}
+
+	if isConnectionFailure(err) {
+		return http.StatusBadGateway
+	}
+
+	return http.StatusInternalServerError
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isConnectionFailure(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isUpstreamFailure determines if the upstream failed to handle a request, as opposed to responding with an error
+// specific to the request (such as a 404 or a 500 for a single broken page) or the client cancelling the request
+func isUpstreamFailure(req *http.Request, resp *http.Response, err error) bool {
+	if isClientCancellation(req, err) {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// isClientCancellation determines if a request to an upstream failed because the client cancelled the original request
+// (e.g. by disconnecting)
+func isClientCancellation(req *http.Request, err error) bool {
+	return errors.Is(err, context.Canceled) && req.Context().Err() != nil
+}
@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUpstreamErrorStatusCode(t *testing.T) {
+	Convey("Given a series of errors returned when sending a request to an upstream", t, func() {
+		testCases := []struct {
+			description string
+			err         error
+			expected    int
+		}{
+			{
+				description: "a context deadline",
+				err:         &url.Error{Op: "Get", URL: "http://babbage", Err: context.DeadlineExceeded},
+				expected:    http.StatusGatewayTimeout,
+			},
+			{
+				description: "a dial timeout",
+				err:         &url.Error{Op: "Get", URL: "http://babbage", Err: &net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}},
+				expected:    http.StatusGatewayTimeout,
+			},
+			{
+				description: "a refused connection",
+				err:         &url.Error{Op: "Get", URL: "http://babbage", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}},
+				expected:    http.StatusBadGateway,
+			},
+			{
+				description: "a DNS error",
+				err:         &url.Error{Op: "Get", URL: "http://babbage", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "babbage"}}},
+				expected:    http.StatusBadGateway,
+			},
+			{
+				description: "an unexpected end of the connection",
+				err:         &url.Error{Op: "Get", URL: "http://babbage", Err: io.EOF},
+				expected:    http.StatusBadGateway,
+			},
+			{
+				description: "an unsupported protocol scheme",
+				err:         &url.Error{Op: "Get", URL: "babbage", Err: errors.New(`unsupported protocol scheme ""`)},
+				expected:    http.StatusInternalServerError,
+			},
+		}
+
+		for _, tc := range testCases {
+			Convey("When the error is "+tc.description, func() {
+				statusCode := upstreamErrorStatusCode(tc.err)
+
+				Convey(fmt.Sprintf("Then the status code should be %d", tc.expected), func() {
+					So(statusCode, ShouldEqual, tc.expected)
+				})
+			})
+		}
+	})
+}
+
+func TestIsUpstreamFailure(t *testing.T) {
+	Convey("Given the outcome of an upstream request", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/some-path", http.NoBody)
+
+		Convey("When the request errored", func() {
+			Convey("Then it is an upstream failure", func() {
+				So(isUpstreamFailure(req, nil, errors.New("connection refused")), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the request was cancelled while the client was still connected", func() {
+			Convey("Then it is an upstream failure", func() {
+				So(isUpstreamFailure(req, nil, &url.Error{Op: "Get", URL: "http://babbage", Err: context.Canceled}), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the request was cancelled because the client went away", func() {
+			cancelledCtx, cancel := context.WithCancel(context.Background())
+			cancel()
+			cancelledReq := req.WithContext(cancelledCtx)
+
+			Convey("Then it is not an upstream failure", func() {
+				So(isUpstreamFailure(cancelledReq, nil, &url.Error{Op: "Get", URL: "http://babbage", Err: context.Canceled}), ShouldBeFalse)
+			})
+		})
+
+		for _, statusCode := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+			Convey(fmt.Sprintf("When the upstream responded with a %d", statusCode), func() {
+				Convey("Then it is an upstream failure", func() {
+					So(isUpstreamFailure(req, &http.Response{StatusCode: statusCode}, nil), ShouldBeTrue)
+				})
+			})
+		}
+
+		for _, statusCode := range []int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError} {
+			Convey(fmt.Sprintf("When the upstream responded with a %d", statusCode), func() {
+				Convey("Then it is not an upstream failure", func() {
+					So(isUpstreamFailure(req, &http.Response{StatusCode: statusCode}, nil), ShouldBeFalse)
+				})
+			})
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package response
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CacheControl is a parsed Cache-Control header (RFC 9111, section 5.2). Directive names are case-insensitive and
+// are kept in lower case, in the order that they were first given.
+type CacheControl struct {
+	directives []cacheControlDirective
+}
+
+type cacheControlDirective struct {
+	name     string
+	value    string
+	hasValue bool
+	quoted   bool
+}
+
+// ParseCacheControl parses the given values of the Cache-Control header, as if they were a single comma-separated
+// list. Quoted values may contain commas and escaped characters. Only the first occurrence of a directive is kept, as
+// recommended when a directive is duplicated.
+func ParseCacheControl(values ...string) *CacheControl {
+	cc := &CacheControl{}
+
+	for _, value := range values {
+		for _, element := range splitCacheControl(value) {
+			name, argument, hasValue := strings.Cut(element, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" || cc.Has(name) {
+				continue
+			}
+
+			directive := cacheControlDirective{name: name, hasValue: hasValue}
+			if hasValue {
+				directive.value, directive.quoted = unquoteCacheControlValue(strings.TrimSpace(argument))
+			}
+			cc.directives = append(cc.directives, directive)
+		}
+	}
+
+	return cc
+}
+
+// splitCacheControl splits a Cache-Control header on the commas that are not in a quoted value
+func splitCacheControl(header string) []string {
+	var elements []string
+	var inQuotes, escaped bool
+	start := 0
+
+	for i := 0; i < len(header); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case inQuotes && header[i] == '\\':
+			escaped = true
+		case header[i] == '"':
+			inQuotes = !inQuotes
+		case header[i] == ',' && !inQuotes:
+			elements = append(elements, strings.TrimSpace(header[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(elements, strings.TrimSpace(header[start:]))
+}
+
+// unquoteCacheControlValue returns the value of a quoted string, without its quotes and escapes, or the value itself
+// if it is a token
+func unquoteCacheControlValue(value string) (string, bool) {
+	if !strings.HasPrefix(value, `"`) {
+		return value, false
+	}
+
+	var unquoted strings.Builder
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if i+1 < len(value) {
+				i++
+				unquoted.WriteByte(value[i])
+			}
+		case '"':
+			return unquoted.String(), true
+		default:
+			unquoted.WriteByte(value[i])
+		}
+	}
+
+	return unquoted.String(), true
+}
+
+// Has determines if the directive is present
+func (cc *CacheControl) Has(name string) bool {
+	return cc.index(name) >= 0
+}
+
+// Get returns the value of the directive, and whether it is present
+func (cc *CacheControl) Get(name string) (string, bool) {
+	if i := cc.index(name); i >= 0 {
+		return cc.directives[i].value, true
+	}
+
+	return "", false
+}
+
+// Set adds the directive without a value, or removes the value of an existing one
+func (cc *CacheControl) Set(name string) {
+	cc.set(cacheControlDirective{name: strings.ToLower(name)})
+}
+
+// SetValue adds the directive with the given value, or replaces the value of an existing one
+func (cc *CacheControl) SetValue(name, value string) {
+	cc.set(cacheControlDirective{name: strings.ToLower(name), value: value, hasValue: true})
+}
+
+// SetSeconds adds the directive with a value of the given number of seconds, or replaces the value of an existing one
+func (cc *CacheControl) SetSeconds(name string, seconds int64) {
+	cc.set(cacheControlDirective{name: strings.ToLower(name), value: strconv.FormatInt(seconds, 10), hasValue: true})
+}
+
+func (cc *CacheControl) set(directive cacheControlDirective) {
+	if i := cc.index(directive.name); i >= 0 {
+		cc.directives[i] = directive
+		return
+	}
+
+	cc.directives = append(cc.directives, directive)
+}
+
+// Del removes the directive
+func (cc *CacheControl) Del(name string) {
+	if i := cc.index(name); i >= 0 {
+		cc.directives = append(cc.directives[:i], cc.directives[i+1:]...)
+	}
+}
+
+// Len returns the number of directives
+func (cc *CacheControl) Len() int {
+	return len(cc.directives)
+}
+
+// String serialises the directives as the value of a Cache-Control header. Values that are not tokens, or that were
+// quoted when they were parsed, are quoted.
+func (cc *CacheControl) String() string {
+	elements := make([]string, 0, len(cc.directives))
+
+	for _, directive := range cc.directives {
+		switch {
+		case !directive.hasValue:
+			elements = append(elements, directive.name)
+		case directive.quoted || !isToken(directive.value):
+			elements = append(elements, directive.name+"="+quoteCacheControlValue(directive.value))
+		default:
+			elements = append(elements, directive.name+"="+directive.value)
+		}
+	}
+
+	return strings.Join(elements, ", ")
+}
+
+func (cc *CacheControl) index(name string) int {
+	for i, directive := range cc.directives {
+		if strings.EqualFold(directive.name, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func quoteCacheControlValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// isToken determines if the value is a token (RFC 9110, section 5.6.2), which does not need to be quoted
+func isToken(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	for _, c := range value {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,111 @@
+package response
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	Convey("Given a series of Cache-Control headers", t, func() {
+		testCases := []struct {
+			cacheControl string
+			expected     string
+		}{
+			{cacheControl: "", expected: ""},
+			{cacheControl: "public", expected: "public"},
+			{cacheControl: "Public, MAX-AGE=60", expected: "public, max-age=60"},
+			{cacheControl: " public ,, max-age = 60 , ", expected: "public, max-age=60"},
+			{cacheControl: "max-age=60, public, max-age=120", expected: "max-age=60, public"},
+			{cacheControl: `private="Set-Cookie, Authorization", max-age=60`, expected: `private="Set-Cookie, Authorization", max-age=60`},
+			{cacheControl: `no-cache="X-\"Quoted\", X-Other", public`, expected: `no-cache="X-\"Quoted\", X-Other", public`},
+			{cacheControl: `community="UCI"`, expected: `community="UCI"`},
+			{cacheControl: `extension=`, expected: `extension=""`},
+			{cacheControl: `extension="unterminated, public`, expected: `extension="unterminated, public"`},
+		}
+
+		Convey("When each header is parsed and serialised", func() {
+			for _, tc := range testCases {
+				result := ParseCacheControl(tc.cacheControl).String()
+				Convey(fmt.Sprintf("Then %q should be serialised as %q", tc.cacheControl, tc.expected), func() {
+					So(result, ShouldEqual, tc.expected)
+				})
+			}
+		})
+	})
+
+	Convey("Given a Cache-Control header split across several values", t, func() {
+		values := []string{"public, max-age=60", "Max-Age=120, must-revalidate"}
+
+		Convey("When it is parsed", func() {
+			cc := ParseCacheControl(values...)
+
+			Convey("Then the values should be treated as a single list, keeping the first of any duplicate", func() {
+				So(cc.String(), ShouldEqual, "public, max-age=60, must-revalidate")
+				So(cc.Len(), ShouldEqual, 3)
+			})
+		})
+	})
+
+	Convey("Given a Cache-Control header with a quoted value", t, func() {
+		cc := ParseCacheControl(`private="Set-Cookie, X-\"Token\"", Max-Age=60`)
+
+		Convey("When its directives are read", func() {
+			private, hasPrivate := cc.Get("Private")
+			maxAge, hasMaxAge := cc.Get("max-age")
+			_, hasNoStore := cc.Get("no-store")
+
+			Convey("Then the quoted value should be unquoted and names should be case-insensitive", func() {
+				So(hasPrivate, ShouldBeTrue)
+				So(private, ShouldEqual, `Set-Cookie, X-"Token"`)
+				So(hasMaxAge, ShouldBeTrue)
+				So(maxAge, ShouldEqual, "60")
+				So(hasNoStore, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestCacheControlModification(t *testing.T) {
+	Convey("Given a parsed Cache-Control header", t, func() {
+		cc := ParseCacheControl("public, max-age=60, must-revalidate")
+
+		Convey("When an existing directive is set", func() {
+			cc.SetSeconds("Max-Age", 900)
+
+			Convey("Then its value should be replaced where it was", func() {
+				So(cc.String(), ShouldEqual, "public, max-age=900, must-revalidate")
+			})
+		})
+
+		Convey("When new directives are set", func() {
+			cc.SetSeconds("s-maxage", 900)
+			cc.Set("immutable")
+			cc.SetValue("no-cache", "Set-Cookie")
+
+			Convey("Then they should be added at the end", func() {
+				So(cc.String(), ShouldEqual, "public, max-age=60, must-revalidate, s-maxage=900, immutable, no-cache=Set-Cookie")
+			})
+		})
+
+		Convey("When a value that is not a token is set", func() {
+			cc.SetValue("private", "Set-Cookie, Authorization")
+
+			Convey("Then it should be quoted", func() {
+				So(cc.String(), ShouldEqual, `public, max-age=60, must-revalidate, private="Set-Cookie, Authorization"`)
+			})
+		})
+
+		Convey("When directives are removed", func() {
+			cc.Del("MAX-AGE")
+			cc.Del("no-store")
+
+			Convey("Then only those that were present should be removed", func() {
+				So(cc.String(), ShouldEqual, "public, must-revalidate")
+				So(cc.Has("max-age"), ShouldBeFalse)
+				So(cc.Has("Public"), ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,433 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/pkg/errors"
+)
+
+// Actions that a cache policy rule can take on a response
+const (
+	CachePolicyFixed       = "fixed"
+	CachePolicyReleaseTime = "release_time"
+	CachePolicyPassThrough = "pass_through"
+)
+
+// Named durations that a cache policy rule can use, which are taken from the configuration
+const (
+	CacheTimeLong              = "long"
+	CacheTimeShort             = "short"
+	CacheTimeDefault           = "default"
+	CacheTimeErrored           = "errored"
+	CacheTimeNotFound          = "not_found"
+	CacheTimePermanentRedirect = "permanent_redirect"
+	CacheTimeTemporaryRedirect = "temporary_redirect"
+)
+
+var cacheableMethods = []string{http.MethodGet, http.MethodHead}
+
+var statusCodeClassRegexp = regexp.MustCompile(`^([1-9])xx$`)
+
+// CachePolicyRule is a single rule of the cache policy. Every match field that is set must match the request (and the
+// upstream's response) for the rule to apply. URIPrefix and URIRegex are matched against the request URI, including
+// its query string. StatusCodes are either single status codes ("404"), classes ("5xx") or ranges ("400-403").
+//
+// A fixed rule sets the max-age to MaxAge, which is either one of the named cache times in the configuration (e.g.
+// "long" or "not_found") or a duration (e.g. "4h"). A release time rule calculates the max-age from the page's release
+// time, using MaxAge (or the default cache time, if it is blank) unless the page is released sooner. A pass through
+// rule leaves the upstream's headers unchanged. Fixed and release time rules only add the max-age if the upstream's
+// Cache-Control header has none of the configured upstream cache directives (by default no-store, no-cache, max-age
+// and s-maxage).
+type CachePolicyRule struct {
+	Name        string   `json:"name"`
+	URIPrefix   string   `json:"uri_prefix,omitempty"`
+	URIRegex    string   `json:"uri_regex,omitempty"`
+	Upstream    string   `json:"upstream,omitempty"`
+	PageType    string   `json:"page_type,omitempty"`
+	Methods     []string `json:"methods,omitempty"`
+	StatusCodes []string `json:"status_codes,omitempty"`
+	Action      string   `json:"action"`
+	MaxAge      string   `json:"max_age,omitempty"`
+}
+
+// CachePolicyFile represents the contents of the file pointed at by CACHE_POLICY_FILE
+type CachePolicyFile struct {
+	Rules []CachePolicyRule `json:"rules"`
+}
+
+// CachePolicy holds the validated, ordered rules used to choose how the Cache-Control header of each response is set.
+// The first rule that matches applies; if none does, the upstream's headers are left unchanged.
+type CachePolicy struct {
+	rules []compiledCachePolicyRule
+}
+
+type compiledCachePolicyRule struct {
+	CachePolicyRule
+	uriRegexp   *regexp.Regexp
+	statusCodes []statusCodeRange
+}
+
+type statusCodeRange struct {
+	from, to int
+}
+
+// DefaultCachePolicyRules returns the cache policy used when no CACHE_POLICY_FILE is configured. Redirects and 404s have
+// their own cache times, so that pages which are about to be published become visible quickly; 404s are cached until
+// the page's release at the latest. Legacy assets, pages from the old ONS website and previous versions of pages never
+// change, so they have a long cache time. The max-age of any other page is calculated from its release time. Responses
+// to other methods, and with a status code that is not cacheable, are left unchanged, although 410s and server errors
+// can be given the errored cache time.
+func DefaultCachePolicyRules(cfg *config.Config) []CachePolicyRule {
+	var rules []CachePolicyRule
+	if cfg.EnableErrorResponseCaching {
+		rules = append(rules, CachePolicyRule{Name: "error responses", StatusCodes: []string{"410", "5xx"}, Methods: cacheableMethods, Action: CachePolicyFixed, MaxAge: CacheTimeErrored})
+	}
+
+	return append(rules, []CachePolicyRule{
+		{Name: "uncacheable status codes", StatusCodes: []string{"300", "303", "305-306", "309-399", "400-403", "405-999"}, Action: CachePolicyPassThrough},
+		{Name: "permanent redirects", StatusCodes: []string{"301", "308"}, Methods: cacheableMethods, Action: CachePolicyFixed, MaxAge: CacheTimePermanentRedirect},
+		{Name: "temporary redirects", StatusCodes: []string{"302", "307"}, Methods: cacheableMethods, Action: CachePolicyFixed, MaxAge: CacheTimeTemporaryRedirect},
+		{Name: "not found", StatusCodes: []string{"404"}, Methods: cacheableMethods, Action: CachePolicyReleaseTime, MaxAge: CacheTimeNotFound},
+		{Name: "legacy assets", URIRegex: `^(/(img|css|scss|js|fonts)/|/favicon\.ico$)`, Methods: cacheableMethods, Action: CachePolicyFixed, MaxAge: CacheTimeLong},
+		{Name: "old ONS website", URIPrefix: "/ons/", Methods: cacheableMethods, Action: CachePolicyFixed, MaxAge: CacheTimeLong},
+		{Name: "previous versions", URIRegex: `/previous/v\d+`, Methods: cacheableMethods, Action: CachePolicyFixed, MaxAge: CacheTimeLong},
+		{Name: "pages", Methods: cacheableMethods, Action: CachePolicyReleaseTime},
+		{Name: "everything else", Action: CachePolicyPassThrough},
+	}...)
+}
+
+// LoadCachePolicy builds the cache policy from the file in the configuration, falling back to the default policy
+func LoadCachePolicy(ctx context.Context, cfg *config.Config) (*CachePolicy, error) {
+	if cfg.CacheTimeJitterPercent < 0 || cfg.CacheTimeJitterPercent >= 100 {
+		return nil, fmt.Errorf("invalid cache time jitter percentage %v, which must be at least 0 and less than 100", cfg.CacheTimeJitterPercent)
+	}
+
+	rules := DefaultCachePolicyRules(cfg)
+
+	if cfg.CachePolicyFile != "" {
+		contents, err := os.ReadFile(cfg.CachePolicyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read the cache policy file")
+		}
+
+		var file CachePolicyFile
+		if err = json.Unmarshal(contents, &file); err != nil {
+			return nil, errors.Wrap(err, "unable to parse the cache policy file")
+		}
+
+		rules = file.Rules
+	}
+
+	policy, overlaps, err := NewCachePolicy(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, overlap := range overlaps {
+		log.Warn(ctx, "overlapping cache policy rules", log.Data{"overlap": overlap})
+	}
+
+	return policy, nil
+}
+
+// NewCachePolicy validates the given rules and returns a CachePolicy. A rule that can never apply, because an earlier
+// rule matches every request that it matches, is an error. Rules that match some of the same URIs with different
+// actions are returned as overlaps, as the order of the rules may then be a mistake.
+func NewCachePolicy(rules []CachePolicyRule) (policy *CachePolicy, overlaps []string, err error) {
+	policy = &CachePolicy{}
+
+	for i, rule := range rules {
+		compiled, err := compileCachePolicyRule(rule)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid cache policy rule %d (%q): %w", i, rule.Name, err)
+		}
+
+		for j := range policy.rules {
+			earlier := &policy.rules[j]
+			if earlier.shadows(&compiled) {
+				return nil, nil, fmt.Errorf("invalid cache policy rule %d (%q): it is unreachable, as rule %d (%q) matches every request that it matches", i, rule.Name, j, earlier.Name)
+			}
+
+			if earlier.overlaps(&compiled) && !earlier.hasSameOutcome(&compiled) {
+				overlaps = append(overlaps, fmt.Sprintf("rule %d (%q) overlaps rule %d (%q), which takes precedence", i, rule.Name, j, earlier.Name))
+			}
+		}
+
+		policy.rules = append(policy.rules, compiled)
+	}
+
+	return policy, overlaps, nil
+}
+
+func compileCachePolicyRule(rule CachePolicyRule) (compiledCachePolicyRule, error) {
+	compiled := compiledCachePolicyRule{CachePolicyRule: rule}
+
+	switch rule.Action {
+	case CachePolicyFixed:
+		if err := validateMaxAge(rule.MaxAge); err != nil {
+			return compiled, err
+		}
+	case CachePolicyReleaseTime:
+		if rule.MaxAge != "" {
+			if err := validateMaxAge(rule.MaxAge); err != nil {
+				return compiled, err
+			}
+		}
+	case CachePolicyPassThrough:
+		if rule.MaxAge != "" {
+			return compiled, fmt.Errorf("a max age cannot be set for a %q rule", CachePolicyPassThrough)
+		}
+	default:
+		return compiled, fmt.Errorf("invalid action %q", rule.Action)
+	}
+
+	if rule.URIRegex != "" {
+		uriRegexp, err := regexp.Compile(rule.URIRegex)
+		if err != nil {
+			return compiled, errors.Wrap(err, "invalid URI regex")
+		}
+		compiled.uriRegexp = uriRegexp
+	}
+
+	for _, method := range rule.Methods {
+		if !config.IsHTTPMethod(method) {
+			return compiled, fmt.Errorf("invalid method %q", method)
+		}
+	}
+
+	for _, statusCodes := range rule.StatusCodes {
+		statusCodeRange, err := parseStatusCodeRange(statusCodes)
+		if err != nil {
+			return compiled, err
+		}
+		compiled.statusCodes = append(compiled.statusCodes, statusCodeRange)
+	}
+
+	return compiled, nil
+}
+
+func validateMaxAge(maxAge string) error {
+	switch maxAge {
+	case CacheTimeLong, CacheTimeShort, CacheTimeDefault, CacheTimeErrored,
+		CacheTimeNotFound, CacheTimePermanentRedirect, CacheTimeTemporaryRedirect:
+		return nil
+	case "":
+		return errors.New("a max age is required")
+	}
+
+	if duration, err := time.ParseDuration(maxAge); err != nil || duration < 0 {
+		return fmt.Errorf("max age %q is neither a named cache time nor a duration", maxAge)
+	}
+
+	return nil
+}
+
+func parseStatusCodeRange(statusCodes string) (statusCodeRange, error) {
+	if match := statusCodeClassRegexp.FindStringSubmatch(statusCodes); match != nil {
+		class, _ := strconv.Atoi(match[1])
+		return statusCodeRange{from: class * 100, to: class*100 + 99}, nil
+	}
+
+	from, to, isRange := strings.Cut(statusCodes, "-")
+	if !isRange {
+		to = from
+	}
+
+	fromCode, fromErr := strconv.Atoi(from)
+	toCode, toErr := strconv.Atoi(to)
+	if fromErr != nil || toErr != nil || fromCode < 100 || toCode > 999 || fromCode > toCode {
+		return statusCodeRange{}, fmt.Errorf("invalid status codes %q", statusCodes)
+	}
+
+	return statusCodeRange{from: fromCode, to: toCode}, nil
+}
+
+// Match returns the first rule that applies to the given request, forwarded to the given upstream, and the status code
+// of the upstream's response. It returns nil if no rule applies.
+func (p *CachePolicy) Match(req *http.Request, upstream string, statusCode int) *CachePolicyRule {
+	pageType := req.Header.Get("Ons-Page-Type")
+
+	for i := range p.rules {
+		if p.rules[i].matches(req.RequestURI, upstream, pageType, req.Method, statusCode) {
+			return &p.rules[i].CachePolicyRule
+		}
+	}
+
+	return nil
+}
+
+// maxAge returns the max-age of a response that matches the fixed rule, or the longest max-age of one that matches the
+// release time rule
+func (r *CachePolicyRule) maxAge(cfg *config.Config) time.Duration {
+	switch r.MaxAge {
+	case "", CacheTimeDefault:
+		return cfg.CacheTimeDefault
+	case CacheTimeLong:
+		return cfg.CacheTimeLong
+	case CacheTimeShort:
+		return cfg.CacheTimeShort
+	case CacheTimeErrored:
+		return cfg.CacheTimeErrored
+	case CacheTimeNotFound:
+		return cfg.CacheTimeNotFound
+	case CacheTimePermanentRedirect:
+		return cfg.CacheTimePermanentRedirect
+	case CacheTimeTemporaryRedirect:
+		return cfg.CacheTimeTemporaryRedirect
+	}
+
+	// The max age has already been validated by NewCachePolicy
+	maxAge, _ := time.ParseDuration(r.MaxAge)
+	return maxAge
+}
+
+// isJittered determines if the rule's max age is the default or long cache time, which are jittered
+func (r *CachePolicyRule) isJittered() bool {
+	return r.MaxAge == "" || r.MaxAge == CacheTimeDefault || r.MaxAge == CacheTimeLong
+}
+
+func (r *compiledCachePolicyRule) matches(uri, upstream, pageType, method string, statusCode int) bool {
+	if r.URIPrefix != "" && !strings.HasPrefix(uri, r.URIPrefix) {
+		return false
+	}
+
+	if r.uriRegexp != nil && !r.uriRegexp.MatchString(uri) {
+		return false
+	}
+
+	if r.Upstream != "" && r.Upstream != upstream {
+		return false
+	}
+
+	if r.PageType != "" && r.PageType != pageType {
+		return false
+	}
+
+	if len(r.Methods) > 0 && !config.ContainsFold(r.Methods, method) {
+		return false
+	}
+
+	if len(r.statusCodes) > 0 && !slices.ContainsFunc(r.statusCodes, func(codes statusCodeRange) bool {
+		return codes.from <= statusCode && statusCode <= codes.to
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// shadows determines if the rule matches every request that the later rule matches. A URI regex is only known to match
+// the same URIs as an identical one.
+func (r *compiledCachePolicyRule) shadows(later *compiledCachePolicyRule) bool {
+	if r.URIPrefix != "" && !strings.HasPrefix(later.URIPrefix, r.URIPrefix) {
+		return false
+	}
+
+	if r.URIRegex != "" && r.URIRegex != later.URIRegex {
+		return false
+	}
+
+	if r.Upstream != "" && r.Upstream != later.Upstream {
+		return false
+	}
+
+	if r.PageType != "" && r.PageType != later.PageType {
+		return false
+	}
+
+	if len(r.Methods) > 0 && (len(later.Methods) == 0 || slices.ContainsFunc(later.Methods, func(method string) bool {
+		return !config.ContainsFold(r.Methods, method)
+	})) {
+		return false
+	}
+
+	if len(r.statusCodes) > 0 && (len(later.statusCodes) == 0 || !coversStatusCodes(r.statusCodes, later.statusCodes)) {
+		return false
+	}
+
+	return true
+}
+
+// overlaps determines if both rules match some of the same URIs, and could apply to the same requests. Only rules with
+// a URI prefix or regex are compared, as rules without one are meant to apply across URIs.
+func (r *compiledCachePolicyRule) overlaps(other *compiledCachePolicyRule) bool {
+	if (r.URIPrefix == "" && r.URIRegex == "") || (other.URIPrefix == "" && other.URIRegex == "") {
+		return false
+	}
+
+	if r.URIPrefix != "" && other.URIPrefix != "" &&
+		!strings.HasPrefix(r.URIPrefix, other.URIPrefix) && !strings.HasPrefix(other.URIPrefix, r.URIPrefix) {
+		return false
+	}
+
+	if r.URIRegex != "" && other.URIRegex != "" && r.URIRegex != other.URIRegex {
+		return false
+	}
+
+	// Whether a regex and a different regex, or no regex, match some of the same URIs can only be told from prefixes
+	if r.URIRegex != other.URIRegex && (r.URIPrefix == "" || other.URIPrefix == "") {
+		return false
+	}
+
+	if r.Upstream != "" && other.Upstream != "" && r.Upstream != other.Upstream {
+		return false
+	}
+
+	if r.PageType != "" && other.PageType != "" && r.PageType != other.PageType {
+		return false
+	}
+
+	if len(r.Methods) > 0 && len(other.Methods) > 0 && !slices.ContainsFunc(r.Methods, func(method string) bool {
+		return config.ContainsFold(other.Methods, method)
+	}) {
+		return false
+	}
+
+	if len(r.statusCodes) > 0 && len(other.statusCodes) > 0 && !intersectStatusCodes(r.statusCodes, other.statusCodes) {
+		return false
+	}
+
+	return true
+}
+
+func (r *compiledCachePolicyRule) hasSameOutcome(other *compiledCachePolicyRule) bool {
+	return r.Action == other.Action && r.MaxAge == other.MaxAge
+}
+
+// coversStatusCodes determines if every status code in the later ranges is in one of the ranges
+func coversStatusCodes(ranges, later []statusCodeRange) bool {
+	for _, laterRange := range later {
+		for statusCode := laterRange.from; statusCode <= laterRange.to; statusCode++ {
+			if !slices.ContainsFunc(ranges, func(codes statusCodeRange) bool {
+				return codes.from <= statusCode && statusCode <= codes.to
+			}) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func intersectStatusCodes(ranges, other []statusCodeRange) bool {
+	for _, codes := range ranges {
+		for _, otherCodes := range other {
+			if codes.from <= otherCodes.to && otherCodes.from <= codes.to {
+				return true
+			}
+		}
+	}
+
+	return false
+}
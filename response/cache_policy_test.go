@@ -0,0 +1,435 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDefaultCachePolicy(t *testing.T) {
+	Convey("Given the default cache policy", t, func() {
+		policy, overlaps, err := NewCachePolicy(DefaultCachePolicyRules(&config.Config{}))
+		So(err, ShouldBeNil)
+
+		Convey("Then it should have no overlapping rules", func() {
+			So(overlaps, ShouldBeEmpty)
+		})
+
+		versionedURIs := []string{
+			"/economy/inflationandpriceindices/bulletins/producerpriceinflation/october2022/previous/v1",
+			"/chartimage?uri=economy/inflationandpriceindices/bulletins/producerpriceinflation/october2022/previous/v1/30d7d6c2",
+			"/economy/inflationandpriceindices/bulletins/producerpriceinflation/october2022/previous/v1/30d7d6c2/data",
+			"/file?uri=/economy/inflationandpriceindices/datasets/consumerpriceindicescpiandretailpricesindexrpilemindicesandpricequotes/pricequotesseptember2023/previous/v1/pricequotes202309.xlsx",
+			"/file?uri=/economy/inflationandpriceindices/datasets/consumerpriceindices/current/previous/v103/mm23.csv",
+		}
+
+		onsURIs := []string{
+			"/ons/rel/household-income/the-effects-of-taxes-and-benefits-on-household-income/index.html",
+			"/ons/rel/integrated-household-survey/integrated-household-survey/index.html",
+		}
+
+		legacyAssetURIs := []string{
+			"/img/national-statistics.png",
+			"/css/main.css",
+			"/scss/some-sass-file.scss",
+			"/js/app.js",
+			"/fonts/open-sans-regular/OpenSans-Regular-webfont.woff2",
+			"/favicon.ico",
+		}
+
+		pageURIs := []string{
+			"/economy/inflationandpriceindices/bulletins/producerpriceinflation/latest",
+			"/favicon.ico.html",
+			"/economy/previous/latest",
+		}
+
+		Convey("When a GET request for a long-lived URI is matched against the policy", func() {
+			for _, uris := range [][]string{versionedURIs, onsURIs, legacyAssetURIs} {
+				for _, uri := range uris {
+					rule := policy.Match(httptest.NewRequest(http.MethodGet, uri, http.NoBody), "babbage", http.StatusOK)
+
+					Convey("Then it should have a long cache time for the following URI: "+uri, func() {
+						So(rule, ShouldNotBeNil)
+						So(rule.Action, ShouldEqual, CachePolicyFixed)
+						So(rule.MaxAge, ShouldEqual, CacheTimeLong)
+					})
+				}
+			}
+		})
+
+		Convey("When a GET request for any other URI is matched against the policy", func() {
+			for _, uri := range pageURIs {
+				rule := policy.Match(httptest.NewRequest(http.MethodGet, uri, http.NoBody), "babbage", http.StatusOK)
+
+				Convey("Then its max-age should be calculated from its release time for the following URI: "+uri, func() {
+					So(rule, ShouldNotBeNil)
+					So(rule.Action, ShouldEqual, CachePolicyReleaseTime)
+				})
+			}
+		})
+
+		Convey("When requests are matched against the policy", func() {
+			testCases := []struct {
+				method     string
+				statusCode int
+				action     string
+				maxAge     string
+			}{
+				{method: http.MethodHead, statusCode: http.StatusOK, action: CachePolicyReleaseTime},
+				{method: http.MethodGet, statusCode: http.StatusNotModified, action: CachePolicyReleaseTime},
+				{method: http.MethodGet, statusCode: http.StatusNotFound, action: CachePolicyReleaseTime, maxAge: CacheTimeNotFound},
+				{method: http.MethodGet, statusCode: http.StatusMovedPermanently, action: CachePolicyFixed, maxAge: CacheTimePermanentRedirect},
+				{method: http.MethodGet, statusCode: http.StatusPermanentRedirect, action: CachePolicyFixed, maxAge: CacheTimePermanentRedirect},
+				{method: http.MethodGet, statusCode: http.StatusFound, action: CachePolicyFixed, maxAge: CacheTimeTemporaryRedirect},
+				{method: http.MethodHead, statusCode: http.StatusTemporaryRedirect, action: CachePolicyFixed, maxAge: CacheTimeTemporaryRedirect},
+				{method: http.MethodGet, statusCode: http.StatusMultipleChoices, action: CachePolicyPassThrough},
+				{method: http.MethodGet, statusCode: http.StatusSeeOther, action: CachePolicyPassThrough},
+				{method: http.MethodGet, statusCode: http.StatusBadRequest, action: CachePolicyPassThrough},
+				{method: http.MethodGet, statusCode: http.StatusMethodNotAllowed, action: CachePolicyPassThrough},
+				{method: http.MethodGet, statusCode: http.StatusGone, action: CachePolicyPassThrough},
+				{method: http.MethodGet, statusCode: http.StatusInternalServerError, action: CachePolicyPassThrough},
+				{method: http.MethodPost, statusCode: http.StatusOK, action: CachePolicyPassThrough},
+				{method: http.MethodDelete, statusCode: http.StatusNotFound, action: CachePolicyPassThrough},
+				{method: http.MethodPut, statusCode: http.StatusMovedPermanently, action: CachePolicyPassThrough},
+			}
+
+			for _, tc := range testCases {
+				rule := policy.Match(httptest.NewRequest(tc.method, "/economy", http.NoBody), "babbage", tc.statusCode)
+
+				Convey("Then the action for a "+tc.method+" request with a status code of "+http.StatusText(tc.statusCode)+" should be "+tc.action, func() {
+					So(rule, ShouldNotBeNil)
+					So(rule.Action, ShouldEqual, tc.action)
+					So(rule.MaxAge, ShouldEqual, tc.maxAge)
+				})
+			}
+		})
+	})
+}
+
+func TestDefaultCachePolicyWithErrorResponseCaching(t *testing.T) {
+	Convey("Given the default cache policy with error response caching enabled", t, func() {
+		policy, overlaps, err := NewCachePolicy(DefaultCachePolicyRules(&config.Config{EnableErrorResponseCaching: true}))
+		So(err, ShouldBeNil)
+		So(overlaps, ShouldBeEmpty)
+
+		Convey("When GET requests with a 410 or 5xx status code are matched against the policy", func() {
+			for _, statusCode := range []int{http.StatusGone, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+				rule := policy.Match(httptest.NewRequest(http.MethodGet, "/economy", http.NoBody), "babbage", statusCode)
+
+				Convey("Then they should have the errored cache time for a status code of "+http.StatusText(statusCode), func() {
+					So(rule.Action, ShouldEqual, CachePolicyFixed)
+					So(rule.MaxAge, ShouldEqual, CacheTimeErrored)
+				})
+			}
+		})
+
+		Convey("When requests with other uncacheable status codes or methods are matched against the policy", func() {
+			badRequest := policy.Match(httptest.NewRequest(http.MethodGet, "/economy", http.NoBody), "babbage", http.StatusBadRequest)
+			post := policy.Match(httptest.NewRequest(http.MethodPost, "/economy", http.NoBody), "babbage", http.StatusInternalServerError)
+
+			Convey("Then they should be passed through", func() {
+				So(badRequest.Action, ShouldEqual, CachePolicyPassThrough)
+				So(post.Action, ShouldEqual, CachePolicyPassThrough)
+			})
+		})
+	})
+}
+
+func TestCachePolicyMatch(t *testing.T) {
+	Convey("Given a cache policy with every kind of match field", t, func() {
+		policy, _, err := NewCachePolicy([]CachePolicyRule{
+			{Name: "server errors", StatusCodes: []string{"5xx"}, Action: CachePolicyPassThrough},
+			{Name: "timeseries", URIPrefix: "/economy/", URIRegex: `/timeseries/`, Upstream: "timeseries-api", Methods: []string{"get"}, Action: CachePolicyFixed, MaxAge: "90s"},
+			{Name: "bulletins", PageType: "bulletin", StatusCodes: []string{"200-299"}, Action: CachePolicyFixed, MaxAge: CacheTimeShort},
+			{Name: "pages", Action: CachePolicyReleaseTime},
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When a request matches every field of a rule", func() {
+			rule := policy.Match(httptest.NewRequest(http.MethodGet, "/economy/timeseries/abmi", http.NoBody), "timeseries-api", http.StatusOK)
+
+			Convey("Then that rule should apply", func() {
+				So(rule.Name, ShouldEqual, "timeseries")
+			})
+		})
+
+		Convey("When a request only matches some fields of a rule", func() {
+			rule := policy.Match(httptest.NewRequest(http.MethodGet, "/economy/timeseries/abmi", http.NoBody), "babbage", http.StatusOK)
+
+			Convey("Then the next matching rule should apply", func() {
+				So(rule.Name, ShouldEqual, "pages")
+			})
+		})
+
+		Convey("When a request has a matching page type", func() {
+			req := httptest.NewRequest(http.MethodGet, "/economy/bulletins/gdp", http.NoBody)
+			req.Header.Set("Ons-Page-Type", "bulletin")
+
+			Convey("Then the page type rule should apply to a response with a status code in its range", func() {
+				So(policy.Match(req, "babbage", http.StatusOK).Name, ShouldEqual, "bulletins")
+			})
+
+			Convey("Then the page type rule should not apply to a response with a status code outside its range", func() {
+				So(policy.Match(req, "babbage", http.StatusNotFound).Name, ShouldEqual, "pages")
+			})
+
+			Convey("Then an earlier rule should take precedence", func() {
+				So(policy.Match(req, "babbage", http.StatusBadGateway).Name, ShouldEqual, "server errors")
+			})
+		})
+	})
+
+	Convey("Given a cache policy without a catch-all rule", t, func() {
+		policy, _, err := NewCachePolicy([]CachePolicyRule{
+			{Name: "assets", URIPrefix: "/img/", Action: CachePolicyFixed, MaxAge: CacheTimeLong},
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When a request does not match any rule", func() {
+			rule := policy.Match(httptest.NewRequest(http.MethodGet, "/economy", http.NoBody), "babbage", http.StatusOK)
+
+			Convey("Then no rule should be returned", func() {
+				So(rule, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestCachePolicyRuleMaxAge(t *testing.T) {
+	Convey("Given a configuration with cache times", t, func() {
+		cfg := &config.Config{
+			CacheTimeDefault:           15 * time.Minute,
+			CacheTimeErrored:           30 * time.Second,
+			CacheTimeLong:              4 * time.Hour,
+			CacheTimeShort:             10 * time.Second,
+			CacheTimeNotFound:          5 * time.Second,
+			CacheTimePermanentRedirect: 2 * time.Hour,
+			CacheTimeTemporaryRedirect: 20 * time.Second,
+		}
+
+		testCases := map[string]time.Duration{
+			CacheTimeDefault:           15 * time.Minute,
+			CacheTimeErrored:           30 * time.Second,
+			CacheTimeLong:              4 * time.Hour,
+			CacheTimeShort:             10 * time.Second,
+			CacheTimeNotFound:          5 * time.Second,
+			CacheTimePermanentRedirect: 2 * time.Hour,
+			CacheTimeTemporaryRedirect: 20 * time.Second,
+			"90s":                      90 * time.Second,
+			"0s":                       0,
+		}
+
+		for maxAge, expected := range testCases {
+			Convey("When the max age of a fixed rule is "+maxAge, func() {
+				rule := &CachePolicyRule{Action: CachePolicyFixed, MaxAge: maxAge}
+
+				Convey("Then the max-age should be "+expected.String(), func() {
+					So(rule.maxAge(cfg), ShouldEqual, expected)
+				})
+			})
+		}
+
+		Convey("When a release time rule has no max age", func() {
+			rule := &CachePolicyRule{Action: CachePolicyReleaseTime}
+
+			Convey("Then the longest max-age should be the default cache time", func() {
+				So(rule.maxAge(cfg), ShouldEqual, 15*time.Minute)
+			})
+		})
+	})
+}
+
+func TestNewCachePolicyValidation(t *testing.T) {
+	Convey("Given a series of invalid rules", t, func() {
+		testCases := map[string][]CachePolicyRule{
+			"invalid action":            {{Name: "a", URIPrefix: "/a", Action: "cache"}},
+			"missing max age":           {{Name: "a", URIPrefix: "/a", Action: CachePolicyFixed}},
+			"invalid max age":           {{Name: "a", URIPrefix: "/a", Action: CachePolicyFixed, MaxAge: "forever"}},
+			"negative max age":          {{Name: "a", URIPrefix: "/a", Action: CachePolicyFixed, MaxAge: "-1s"}},
+			"max age for pass through":  {{Name: "a", URIPrefix: "/a", Action: CachePolicyPassThrough, MaxAge: CacheTimeLong}},
+			"invalid release time cap":  {{Name: "a", URIPrefix: "/a", Action: CachePolicyReleaseTime, MaxAge: "soon"}},
+			"invalid regex":             {{Name: "a", URIRegex: "(", Action: CachePolicyPassThrough}},
+			"invalid method":            {{Name: "a", Methods: []string{"FETCH"}, Action: CachePolicyPassThrough}},
+			"invalid status code":       {{Name: "a", StatusCodes: []string{"abc"}, Action: CachePolicyPassThrough}},
+			"invalid status code range": {{Name: "a", StatusCodes: []string{"404-400"}, Action: CachePolicyPassThrough}},
+			"out of range status code":  {{Name: "a", StatusCodes: []string{"1000"}, Action: CachePolicyPassThrough}},
+			"rule after a catch-all": {
+				{Name: "a", Action: CachePolicyReleaseTime},
+				{Name: "b", URIPrefix: "/b", Action: CachePolicyPassThrough},
+			},
+			"rule shadowed by a shorter prefix": {
+				{Name: "a", URIPrefix: "/economy/", Action: CachePolicyReleaseTime},
+				{Name: "b", URIPrefix: "/economy/timeseries/", Methods: []string{http.MethodGet}, Action: CachePolicyFixed, MaxAge: CacheTimeShort},
+			},
+			"rule shadowed by a wider status code class": {
+				{Name: "a", StatusCodes: []string{"4xx"}, Methods: []string{http.MethodGet, http.MethodHead}, Action: CachePolicyPassThrough},
+				{Name: "b", URIRegex: "^/a", StatusCodes: []string{"404"}, Methods: []string{http.MethodGet}, Action: CachePolicyReleaseTime},
+			},
+			"duplicate rule": {
+				{Name: "a", URIRegex: "^/a", Action: CachePolicyPassThrough},
+				{Name: "b", URIRegex: "^/a", Action: CachePolicyReleaseTime},
+			},
+		}
+
+		for description, rules := range testCases {
+			Convey("When a cache policy is created with a "+description, func() {
+				policy, _, err := NewCachePolicy(rules)
+
+				Convey("Then an error should be returned", func() {
+					So(err, ShouldNotBeNil)
+					So(policy, ShouldBeNil)
+				})
+			})
+		}
+	})
+
+	Convey("Given a series of reachable rules", t, func() {
+		testCases := map[string][]CachePolicyRule{
+			"longer prefix first": {
+				{Name: "a", URIPrefix: "/economy/timeseries/", Action: CachePolicyFixed, MaxAge: CacheTimeShort},
+				{Name: "b", URIPrefix: "/economy/", Action: CachePolicyReleaseTime},
+			},
+			"narrower methods first": {
+				{Name: "a", Methods: []string{http.MethodGet}, Action: CachePolicyReleaseTime},
+				{Name: "b", Methods: []string{http.MethodGet, http.MethodHead}, Action: CachePolicyPassThrough},
+			},
+			"narrower status codes first": {
+				{Name: "a", StatusCodes: []string{"404"}, Action: CachePolicyFixed, MaxAge: CacheTimeDefault},
+				{Name: "b", StatusCodes: []string{"400-403", "404", "405-499"}, Action: CachePolicyPassThrough},
+				{Name: "c", StatusCodes: []string{"4xx", "5xx"}, Action: CachePolicyFixed, MaxAge: CacheTimeErrored},
+			},
+			"different upstreams": {
+				{Name: "a", Upstream: "babbage", Action: CachePolicyReleaseTime},
+				{Name: "b", Upstream: "release-calendar", Action: CachePolicyPassThrough},
+			},
+		}
+
+		for description, rules := range testCases {
+			Convey("When a cache policy is created with the "+description, func() {
+				policy, _, err := NewCachePolicy(rules)
+
+				Convey("Then no error should be returned", func() {
+					So(err, ShouldBeNil)
+					So(policy, ShouldNotBeNil)
+				})
+			})
+		}
+	})
+}
+
+func TestNewCachePolicyOverlaps(t *testing.T) {
+	Convey("Given rules whose URIs overlap", t, func() {
+		rules := []CachePolicyRule{
+			{Name: "timeseries", URIPrefix: "/economy/timeseries/", Action: CachePolicyFixed, MaxAge: CacheTimeShort},
+			{Name: "economy", URIPrefix: "/economy/", Methods: []string{http.MethodGet}, Action: CachePolicyFixed, MaxAge: CacheTimeLong},
+			{Name: "economy assets", URIPrefix: "/economy/", URIRegex: `\.png$`, Action: CachePolicyFixed, MaxAge: CacheTimeShort},
+			{Name: "pages", Action: CachePolicyReleaseTime},
+		}
+
+		Convey("When a cache policy is created", func() {
+			policy, overlaps, err := NewCachePolicy(rules)
+
+			Convey("Then the rules with different actions should be reported as overlapping", func() {
+				So(err, ShouldBeNil)
+				So(policy, ShouldNotBeNil)
+				So(overlaps, ShouldResemble, []string{
+					`rule 1 ("economy") overlaps rule 0 ("timeseries"), which takes precedence`,
+					`rule 2 ("economy assets") overlaps rule 1 ("economy"), which takes precedence`,
+				})
+			})
+		})
+	})
+
+	Convey("Given rules whose URIs overlap but whose methods do not", t, func() {
+		rules := []CachePolicyRule{
+			{Name: "timeseries", URIPrefix: "/economy/timeseries/", Methods: []string{http.MethodHead}, Action: CachePolicyFixed, MaxAge: CacheTimeShort},
+			{Name: "economy", URIPrefix: "/economy/", Methods: []string{http.MethodGet}, Action: CachePolicyFixed, MaxAge: CacheTimeLong},
+		}
+
+		Convey("When a cache policy is created", func() {
+			_, overlaps, err := NewCachePolicy(rules)
+
+			Convey("Then no overlaps should be reported", func() {
+				So(err, ShouldBeNil)
+				So(overlaps, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestLoadCachePolicyFromFile(t *testing.T) {
+	Convey("Given a cache policy file", t, func() {
+		ctx := context.Background()
+		cachePolicyFile := filepath.Join(t.TempDir(), "cache-policy.json")
+		cfg := &config.Config{CachePolicyFile: cachePolicyFile}
+
+		Convey("When the file contains a valid cache policy", func() {
+			err := os.WriteFile(cachePolicyFile, []byte(`{"rules": [{"name": "assets", "uri_prefix": "/img/", "action": "fixed", "max_age": "1h"}]}`), 0o600)
+			So(err, ShouldBeNil)
+			policy, err := LoadCachePolicy(ctx, cfg)
+
+			Convey("Then the rules in the file are used", func() {
+				So(err, ShouldBeNil)
+				So(policy.rules, ShouldHaveLength, 1)
+				So(policy.rules[0].Name, ShouldEqual, "assets")
+				So(policy.rules[0].MaxAge, ShouldEqual, "1h")
+			})
+		})
+
+		Convey("When the file contains an invalid cache policy", func() {
+			err := os.WriteFile(cachePolicyFile, []byte(`{"rules": [{"name": "assets", "uri_prefix": "/img/", "action": "fixed"}]}`), 0o600)
+			So(err, ShouldBeNil)
+			_, err = LoadCachePolicy(ctx, cfg)
+
+			Convey("Then an error should be returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When the file is not valid JSON", func() {
+			err := os.WriteFile(cachePolicyFile, []byte(`rules:`), 0o600)
+			So(err, ShouldBeNil)
+			_, err = LoadCachePolicy(ctx, cfg)
+
+			Convey("Then an error should be returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When the file does not exist", func() {
+			cfg.CachePolicyFile = filepath.Join(t.TempDir(), "missing.json")
+			_, err := LoadCachePolicy(ctx, cfg)
+
+			Convey("Then an error should be returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+
+	Convey("Given an invalid cache time jitter percentage", t, func() {
+		for _, percent := range []float64{-1, 100} {
+			_, err := LoadCachePolicy(context.Background(), &config.Config{CacheTimeJitterPercent: percent})
+
+			Convey(fmt.Sprintf("Then an error should be returned for %v", percent), func() {
+				So(err, ShouldNotBeNil)
+			})
+		}
+	})
+
+	Convey("Given no cache policy file", t, func() {
+		policy, err := LoadCachePolicy(context.Background(), &config.Config{})
+
+		Convey("Then the default cache policy is used", func() {
+			So(err, ShouldBeNil)
+			So(policy.rules, ShouldHaveLength, len(DefaultCachePolicyRules(&config.Config{})))
+		})
+	})
+}
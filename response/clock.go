@@ -0,0 +1,114 @@
+package response
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/pkg/errors"
+)
+
+// TimeTravelHeader is the request header that sets the time used to calculate the cache headers of a single response,
+// when time travel is enabled. Its value is an RFC 3339 time (e.g. 2024-02-15T09:29:30Z). It is only honoured if the
+// request has the admin auth token as its bearer token.
+const TimeTravelHeader = "X-Time-Travel"
+
+// timeTravelEnvironments are the only environments that time travel can be enabled in
+var timeTravelEnvironments = []string{"local", "sandbox", "staging"}
+
+// Clock tells the time that the cache headers are calculated from. It is the real time unless time travel is enabled,
+// in which case it can be shifted for every response (by an admin setting) or for a single response (by the time
+// travel header), e.g. to rehearse a release in a pre-production environment.
+type Clock struct {
+	mutex      sync.RWMutex
+	now        func() time.Time
+	timeTravel bool
+	authToken  string
+	offset     time.Duration
+}
+
+// NewClock creates the clock in the configuration. An error is returned if time travel is enabled in an environment
+// other than the pre-production ones, or without an admin auth token.
+func NewClock(cfg *config.Config) (*Clock, error) {
+	if cfg.EnableTimeTravel {
+		if !config.ContainsFold(timeTravelEnvironments, cfg.Environment) {
+			return nil, errors.Errorf("time travel can only be enabled in the %s environments, not %q", strings.Join(timeTravelEnvironments, ", "), cfg.Environment)
+		}
+		if cfg.AdminAuthToken == "" {
+			return nil, errors.New("time travel can only be enabled when the admin auth token is set")
+		}
+	}
+
+	return &Clock{now: time.Now, timeTravel: cfg.EnableTimeTravel, authToken: cfg.AdminAuthToken}, nil
+}
+
+// TimeTravelEnabled determines if the clock can be shifted
+func (c *Clock) TimeTravelEnabled() bool {
+	return c.timeTravel
+}
+
+// Now returns the time to calculate the cache headers of the response to the given request from
+func (c *Clock) Now(ctx context.Context, req *http.Request) time.Time {
+	if !c.timeTravel {
+		return c.now()
+	}
+
+	if header := strings.TrimSpace(req.Header.Get(TimeTravelHeader)); header != "" {
+		if !c.isAuthorised(req) {
+			log.Warn(ctx, "ignoring an unauthorised time travel header", log.Data{"value": header})
+			return c.Current()
+		}
+
+		now, err := time.Parse(time.RFC3339, header)
+		if err == nil {
+			log.Info(ctx, "time travelling for a single request", log.Data{"now": now})
+			return now
+		}
+		log.Warn(ctx, "ignoring an invalid time travel header", log.Data{"value": header, "error": err.Error()})
+	}
+
+	return c.Current()
+}
+
+// isAuthorised determines if the request has the admin auth token as its bearer token
+func (c *Clock) isAuthorised(req *http.Request) bool {
+	authToken, isBearer := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return isBearer && c.authToken != "" && subtle.ConstantTimeCompare([]byte(authToken), []byte(c.authToken)) == 1
+}
+
+// Current returns the time of the clock, including any shift by the admin setting but not by the time travel header
+func (c *Clock) Current() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.now().Add(c.offset)
+}
+
+// TravelTo shifts the clock, so that it is now the given time
+func (c *Clock) TravelTo(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.offset = now.Sub(c.now())
+}
+
+// Reset sets the clock back to the real time
+func (c *Clock) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.offset = 0
+}
+
+// Offset returns how far the clock has been shifted from the real time
+func (c *Clock) Offset() time.Duration {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.offset
+}
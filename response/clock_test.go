@@ -0,0 +1,132 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewClock(t *testing.T) {
+	Convey("Given time travel is disabled", t, func() {
+		clock, err := NewClock(&config.Config{Environment: "prod"})
+
+		Convey("Then the clock should be created without time travel", func() {
+			So(err, ShouldBeNil)
+			So(clock.TimeTravelEnabled(), ShouldBeFalse)
+		})
+	})
+
+	Convey("Given time travel is enabled in a pre-production environment", t, func() {
+		clock, err := NewClock(&config.Config{Environment: "staging", EnableTimeTravel: true, AdminAuthToken: "some-token"})
+
+		Convey("Then the clock should be created with time travel", func() {
+			So(err, ShouldBeNil)
+			So(clock.TimeTravelEnabled(), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given time travel is enabled in production, or any environment that is not pre-production", t, func() {
+		for _, environment := range []string{"prod", "Production", "prod-eu", "live", ""} {
+			_, err := NewClock(&config.Config{Environment: environment, EnableTimeTravel: true, AdminAuthToken: "some-token"})
+
+			Convey("Then an error should be returned for "+environment, func() {
+				So(err, ShouldNotBeNil)
+			})
+		}
+	})
+
+	Convey("Given time travel is enabled without an admin auth token", t, func() {
+		_, err := NewClock(&config.Config{Environment: "staging", EnableTimeTravel: true})
+
+		Convey("Then an error should be returned", func() {
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestClockNow(t *testing.T) {
+	realNow := time.Date(2024, time.February, 15, 8, 0, 0, 0, time.UTC)
+	rehearsal := time.Date(2024, time.February, 15, 9, 29, 30, 0, time.UTC)
+
+	Convey("Given a clock with time travel disabled", t, func() {
+		ctx := context.Background()
+		clock, err := NewClock(&config.Config{})
+		So(err, ShouldBeNil)
+		clock.now = func() time.Time { return realNow }
+
+		Convey("When a request has the time travel header", func() {
+			req := httptest.NewRequest(http.MethodGet, "/economy", http.NoBody)
+			req.Header.Set(TimeTravelHeader, rehearsal.Format(time.RFC3339))
+
+			Convey("Then the real time should be used", func() {
+				So(clock.Now(ctx, req), ShouldEqual, realNow)
+			})
+		})
+	})
+
+	Convey("Given a clock with time travel enabled", t, func() {
+		ctx := context.Background()
+		clock, err := NewClock(&config.Config{Environment: "staging", EnableTimeTravel: true, AdminAuthToken: "some-token"})
+		So(err, ShouldBeNil)
+		clock.now = func() time.Time { return realNow }
+		req := httptest.NewRequest(http.MethodGet, "/economy", http.NoBody)
+		req.Header.Set("Authorization", "Bearer some-token")
+
+		Convey("When a request does not have the time travel header", func() {
+			Convey("Then the real time should be used", func() {
+				So(clock.Now(ctx, req), ShouldEqual, realNow)
+			})
+		})
+
+		Convey("When a request has the time travel header", func() {
+			req.Header.Set(TimeTravelHeader, rehearsal.Format(time.RFC3339))
+
+			Convey("Then the header's time should be used for that request only", func() {
+				So(clock.Now(ctx, req), ShouldEqual, rehearsal)
+				So(clock.Current(), ShouldEqual, realNow)
+			})
+		})
+
+		Convey("When a request has the time travel header without the admin auth token", func() {
+			req.Header.Set(TimeTravelHeader, rehearsal.Format(time.RFC3339))
+			req.Header.Set("Authorization", "Bearer wrong-token")
+
+			Convey("Then the header should be ignored", func() {
+				So(clock.Now(ctx, req), ShouldEqual, realNow)
+			})
+		})
+
+		Convey("When a request has an invalid time travel header", func() {
+			req.Header.Set(TimeTravelHeader, "tomorrow")
+
+			Convey("Then the header should be ignored", func() {
+				So(clock.Now(ctx, req), ShouldEqual, realNow)
+			})
+		})
+
+		Convey("When the clock is shifted", func() {
+			clock.TravelTo(rehearsal)
+
+			Convey("Then every request should use the shifted time, which keeps moving", func() {
+				So(clock.Offset(), ShouldEqual, 89*time.Minute+30*time.Second)
+				So(clock.Now(ctx, req), ShouldEqual, rehearsal)
+				clock.now = func() time.Time { return realNow.Add(30 * time.Second) }
+				So(clock.Now(ctx, req), ShouldEqual, rehearsal.Add(30*time.Second))
+			})
+
+			Convey("And then reset", func() {
+				clock.Reset()
+
+				Convey("Then the real time should be used again", func() {
+					So(clock.Offset(), ShouldEqual, 0)
+					So(clock.Now(ctx, req), ShouldEqual, realNow)
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,37 @@
+package response
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// hopByHopHeaders are the headers that only apply to a single connection (RFC 7230, section 6.1) and must not be
+// forwarded by a proxy
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// RemoveHopByHopHeaders removes the hop-by-hop headers from the given header, including any header named in the
+// Connection header
+func RemoveHopByHopHeaders(header http.Header) {
+	for _, connectionValue := range header.Values("Connection") {
+		for _, name := range strings.Split(connectionValue, ",") {
+			if name = textproto.TrimString(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+
+	for _, name := range hopByHopHeaders {
+		header.Del(name)
+	}
+}
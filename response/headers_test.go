@@ -0,0 +1,33 @@
+package response
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRemoveHopByHopHeaders(t *testing.T) {
+	Convey("Given a header with hop-by-hop and end-to-end headers", t, func() {
+		header := http.Header{}
+		header.Set("Connection", "keep-alive, X-Custom-Hop")
+		header.Set("Keep-Alive", "timeout=5")
+		header.Set("Te", "trailers")
+		header.Set("Transfer-Encoding", "chunked")
+		header.Set("Upgrade", "h2c")
+		header.Set("Proxy-Authorization", "Basic abc")
+		header.Set("X-Custom-Hop", "value")
+		header.Set("Cache-Control", "public")
+		header.Set("ETag", "abc123")
+
+		Convey("When 'RemoveHopByHopHeaders' is called", func() {
+			RemoveHopByHopHeaders(header)
+
+			Convey("Then only the end-to-end headers remain", func() {
+				So(header, ShouldHaveLength, 2)
+				So(header.Get("Cache-Control"), ShouldEqual, "public")
+				So(header.Get("ETag"), ShouldEqual, "abc123")
+			})
+		})
+	})
+}
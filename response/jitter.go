@@ -0,0 +1,26 @@
+package response
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// jitterSteps is the number of steps between no jitter and the full jitter percentage
+const jitterSteps = 10000
+
+// jitter spreads cacheTime by up to the given percentage either way, so that pages cached at the same moment do not
+// all expire together. The jitter is derived from the page's path, so every response for a page gets the same
+// cache time.
+func jitter(cacheTime time.Duration, path string, percent float64) time.Duration {
+	if percent <= 0 {
+		return cacheTime
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(path))
+	// A fraction between -1 and 1, which is the same for every request for the path. The low bits of the hash are used,
+	// as they vary the most between similar paths.
+	fraction := float64(hash.Sum64()%(2*jitterSteps+1))/jitterSteps - 1
+
+	return cacheTime + time.Duration(float64(cacheTime)*percent/100*fraction)
+}
@@ -0,0 +1,42 @@
+package response
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJitter(t *testing.T) {
+	Convey("Given a cache time of 15 minutes", t, func() {
+		cacheTime := 15 * time.Minute
+
+		Convey("When no jitter percentage is configured", func() {
+			Convey("Then the cache time should be unchanged", func() {
+				So(jitter(cacheTime, "/economy", 0), ShouldEqual, cacheTime)
+			})
+		})
+
+		Convey("When a jitter percentage of 10 is configured", func() {
+			Convey("Then the cache time of every path should be within 10% of it", func() {
+				for i := 0; i < 100; i++ {
+					path := fmt.Sprintf("/economy/page%d", i)
+					So(jitter(cacheTime, path, 10), ShouldBeBetweenOrEqual, 810*time.Second, 990*time.Second)
+				}
+			})
+
+			Convey("Then the cache time should be the same for every request for a path", func() {
+				So(jitter(cacheTime, "/economy", 10), ShouldEqual, jitter(cacheTime, "/economy", 10))
+			})
+
+			Convey("Then the cache times of different paths should be spread out", func() {
+				cacheTimes := map[time.Duration]bool{}
+				for i := 0; i < 100; i++ {
+					cacheTimes[jitter(cacheTime, fmt.Sprintf("/economy/page%d", i), 10).Truncate(time.Second)] = true
+				}
+				So(len(cacheTimes), ShouldBeGreaterThan, 50)
+			})
+		})
+	})
+}
@@ -4,89 +4,89 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
 	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/pkg/errors"
 )
 
 const maxAgeErrorMessage = "error calculating the max-age directive"
 
-var versionedURIRegexp = regexp.MustCompile(`/previous/v\d+`)
+// calculatedMaxAge is the max-age of a page, calculated from its release time
+type calculatedMaxAge struct {
+	seconds int
+	// countdownTo is the page's release time, if the max-age counts down to it
+	countdownTo time.Time
+	// releaseTime is the page's release time, if it is upcoming or within the publish expiry offset
+	releaseTime time.Time
+	// pagePath is the path of the page that the release time was looked up for, if it is known
+	pagePath string
+	// releaseTimeUnknown is set when the page's release time could not be looked up, so the page may be about to be
+	// released
+	releaseTimeUnknown bool
+}
 
-func maxAge(ctx context.Context, uri string, cfg *config.Config) (int, bool) {
+// maxAge calculates the max-age, as of now, of the page at the given URI from its release time, which is at most
+// cacheTime
+func maxAge(ctx context.Context, uri string, cacheTime time.Duration, now time.Time, cfg *config.Config, releaseTimes ReleaseTimeSource) calculatedMaxAge {
 	log.Info(ctx, "calculating max-age", log.Data{"uri": uri})
 
-	if isLegacyAssetURI(uri) || isOnsURI(uri) || isVersionedURI(uri) {
-		return int(cfg.CacheTimeLong.Seconds()), false
-	}
-
 	pagePath, err := getPagePath(ctx, uri)
 	if err != nil {
 		log.Error(ctx, maxAgeErrorMessage, err)
-		return int(cfg.CacheTimeErrored.Seconds()), false
+		return calculatedMaxAge{seconds: int(cfg.CacheTimeErrored.Seconds()), releaseTimeUnknown: true}
 	}
 	log.Info(ctx, "calculated page path", log.Data{"path": pagePath})
 
-	releaseTime, statusCode, err := getReleaseTime(pagePath, cfg.LegacyCacheAPIURL)
+	result := pageMaxAge(ctx, pagePath, cacheTime, now, cfg, releaseTimes)
+	result.pagePath = pagePath
+	return result
+}
+
+// pageMaxAge calculates the max-age, as of now, of the page at the given path from its release time
+func pageMaxAge(ctx context.Context, pagePath string, cacheTime time.Duration, now time.Time, cfg *config.Config, releaseTimes ReleaseTimeSource) calculatedMaxAge {
+	releaseTime, statusCode, err := releaseTimes.GetReleaseTime(ctx, pagePath)
+	if errors.Is(err, ErrReleaseTimeSourceUnavailable) {
+		log.Warn(ctx, "issuing degraded max-age", log.Data{"reason": err.Error()})
+		return calculatedMaxAge{seconds: int(cfg.LegacyCacheAPIDegradedMaxAge.Seconds()), releaseTimeUnknown: true}
+	}
 	if err != nil {
 		log.Error(ctx, maxAgeErrorMessage, err)
-		return int(cfg.CacheTimeErrored.Seconds()), false
+		return calculatedMaxAge{seconds: int(cfg.CacheTimeErrored.Seconds()), releaseTimeUnknown: true}
 	}
 
 	if statusCode == http.StatusNotFound {
-		return int(cfg.CacheTimeDefault.Seconds()), false
+		return calculatedMaxAge{seconds: int(cacheTime.Seconds())}
 	}
 
 	if statusCode != http.StatusOK {
 		unexpectedStatusCodeError := fmt.Errorf("unexpected Legacy Cache API status code: %d", statusCode)
 		log.Error(ctx, maxAgeErrorMessage, unexpectedStatusCodeError)
-		return int(cfg.CacheTimeErrored.Seconds()), false
+		return calculatedMaxAge{seconds: int(cfg.CacheTimeErrored.Seconds()), releaseTimeUnknown: true}
 	}
 
 	if releaseTime.IsZero() {
-		return int(cfg.CacheTimeDefault.Seconds()), false
+		return calculatedMaxAge{seconds: int(cacheTime.Seconds())}
 	}
 
-	if releaseTime.After(time.Now()) {
-		if calculatedCacheTime := time.Until(releaseTime); calculatedCacheTime < cfg.CacheTimeDefault {
+	if releaseTime.After(now) {
+		if calculatedCacheTime := releaseTime.Sub(now); calculatedCacheTime < cacheTime {
 			log.Info(ctx, "issuing cache countdown time")
-			return int(calculatedCacheTime.Seconds()), true
+			return calculatedMaxAge{seconds: int(calculatedCacheTime.Seconds()), countdownTo: releaseTime, releaseTime: releaseTime}
 		}
 
-		return int(cfg.CacheTimeDefault.Seconds()), false
+		return calculatedMaxAge{seconds: int(cacheTime.Seconds()), releaseTime: releaseTime}
 	}
 
-	if cfg.EnablePublishExpiryOffset && wasReleasedRecently(releaseTime, cfg.PublishExpiryOffset) {
+	if cfg.EnablePublishExpiryOffset && wasReleasedRecently(releaseTime, cfg.PublishExpiryOffset, now) {
 		log.Info(ctx, "issuing post publish microcache")
-		return int(cfg.CacheTimeShort.Seconds()), false
-	}
-
-	return int(cfg.CacheTimeDefault.Seconds()), false
-}
-
-func isLegacyAssetURI(uri string) bool {
-	legacyAssetFolders := []string{"/img/", "/css/", "/scss/", "/js/", "/fonts/"}
-
-	for _, folder := range legacyAssetFolders {
-		if strings.HasPrefix(uri, folder) {
-			return true
-		}
+		return calculatedMaxAge{seconds: int(min(cfg.CacheTimeShort, cacheTime).Seconds()), releaseTime: releaseTime}
 	}
 
-	return uri == "/favicon.ico"
-}
-
-func isOnsURI(uri string) bool {
-	return strings.HasPrefix(uri, "/ons/")
-}
-
-func isVersionedURI(uri string) bool {
-	return versionedURIRegexp.MatchString(uri)
+	return calculatedMaxAge{seconds: int(cacheTime.Seconds())}
 }
 
-func wasReleasedRecently(releaseTime time.Time, offset time.Duration) bool {
-	return releaseTime.Add(offset).After(time.Now())
+func wasReleasedRecently(releaseTime time.Time, offset time.Duration, now time.Time) bool {
+	return releaseTime.Add(offset).After(now)
 }
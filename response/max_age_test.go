@@ -2,6 +2,7 @@ package response
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -12,57 +13,6 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 )
 
-func TestMaxAgeLongCacheTime(t *testing.T) {
-	Convey("Given a list of URIs and a pre-configured long cache time", t, func() {
-		ctx := context.Background()
-		const longCacheTime = 9999
-		cfg := &config.Config{
-			CacheTimeLong: time.Duration(longCacheTime) * time.Second,
-		}
-
-		versionedURIs := []string{
-			"/economy/inflationandpriceindices/bulletins/producerpriceinflation/october2022/previous/v1",
-			"/chartimage?uri=economy/inflationandpriceindices/bulletins/producerpriceinflation/october2022/previous/v1/30d7d6c2",
-			"/economy/inflationandpriceindices/bulletins/producerpriceinflation/october2022/previous/v1/30d7d6c2/data",
-			"/file?uri=/economy/inflationandpriceindices/datasets/consumerpriceindicescpiandretailpricesindexrpilemindicesandpricequotes/pricequotesseptember2023/previous/v1/pricequotes202309.xlsx",
-			"/file?uri=/economy/inflationandpriceindices/datasets/consumerpriceindices/current/previous/v103/mm23.csv",
-		}
-
-		onsURIs := []string{
-			"/ons/rel/household-income/the-effects-of-taxes-and-benefits-on-household-income/index.html",
-			"/ons/rel/integrated-household-survey/integrated-household-survey/index.html",
-		}
-
-		legacyAssetURIs := []string{
-			"/img/national-statistics.png",
-			"/css/main.css",
-			"/scss/some-sass-file.scss",
-			"/js/app.js",
-			"/fonts/open-sans-regular/OpenSans-Regular-webfont.woff2",
-			"/favicon.ico",
-		}
-
-		groupedTestCases := [][]string{
-			versionedURIs,
-			onsURIs,
-			legacyAssetURIs,
-		}
-
-		Convey("When the 'maxAge' function is called", func() {
-			for _, testCases := range groupedTestCases {
-				for _, uri := range testCases {
-					result, isCalculated := maxAge(ctx, uri, cfg)
-
-					Convey("Then it should return a long cache time for the following URI: "+uri, func() {
-						So(result, ShouldEqual, longCacheTime)
-						So(isCalculated, ShouldBeFalse)
-					})
-				}
-			}
-		})
-	})
-}
-
 func TestMaxAgeInteractionWithLegacyCacheAPI(t *testing.T) {
 	Convey("Given a Legacy Cache API and some pre-configured cache time values", t, func() {
 		ctx := context.Background()
@@ -107,41 +57,81 @@ func TestMaxAgeInteractionWithLegacyCacheAPI(t *testing.T) {
 
 		Convey("When the 'maxAge' function is called and there is a problem trying to retrieve a Cache Time resource", func() {
 			setMockResponseBody("invalid response")
-			result, isCalculated := maxAge(ctx, "/some-valid-url", cfg)
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
 
 			Convey("Then it should return an errored cache time", func() {
-				So(result, ShouldEqual, erroredCacheTime)
-				So(isCalculated, ShouldBeFalse)
+				So(result.seconds, ShouldEqual, erroredCacheTime)
+				So(result.countdownTo, ShouldBeZeroValue)
 			})
 		})
 
 		Convey("When the 'maxAge' function is called and there is a problem with the API", func() {
 			setMockResponseStatusCode(http.StatusInternalServerError)
-			result, isCalculated := maxAge(ctx, "/some-valid-url", cfg)
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
 
 			Convey("Then it should return an errored cache time", func() {
-				So(result, ShouldEqual, erroredCacheTime)
-				So(isCalculated, ShouldBeFalse)
+				So(result.seconds, ShouldEqual, erroredCacheTime)
+				So(result.countdownTo, ShouldBeZeroValue)
+			})
+		})
+
+		Convey("When the 'maxAge' function is called and the API takes longer than the timeout to respond", func() {
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+				w.WriteHeader(http.StatusNotFound)
+			})
+			cfg.LegacyCacheAPITimeout = 50 * time.Millisecond
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
+
+			Convey("Then it should return an errored cache time", func() {
+				So(result.seconds, ShouldEqual, erroredCacheTime)
+				So(result.countdownTo, ShouldBeZeroValue)
+			})
+		})
+
+		Convey("When the 'maxAge' function is called with a request context that is cancelled", func() {
+			setMockResponseStatusCode(http.StatusNotFound)
+			cancelledCtx, cancel := context.WithCancel(ctx)
+			cancel()
+			result := maxAge(cancelledCtx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
+
+			Convey("Then it should return an errored cache time", func() {
+				So(result.seconds, ShouldEqual, erroredCacheTime)
+				So(result.countdownTo, ShouldBeZeroValue)
+			})
+		})
+
+		Convey("When the 'maxAge' function is called and the circuit of the API is open", func() {
+			setMockResponseStatusCode(http.StatusInternalServerError)
+			cfg.LegacyCacheAPICircuitBreaker = config.CircuitBreaker{Enabled: true, FailureRatio: 0.5, MinRequests: 1, Window: time.Minute, OpenDuration: time.Hour}
+			cfg.LegacyCacheAPIDegradedMaxAge = 20 * time.Second
+			releaseTimes := NewReleaseTimeCache(cfg, NewGuardedReleaseTimeSource(ReleaseTimeSourceLegacyCacheAPI, NewLegacyCacheAPISource(cfg), cfg.LegacyCacheAPICircuitBreaker))
+			_ = maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, releaseTimes)
+			result := maxAge(ctx, "/some-other-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, releaseTimes)
+
+			Convey("Then it should return the degraded max-age", func() {
+				So(result.seconds, ShouldEqual, 20)
+				So(result.countdownTo, ShouldBeZeroValue)
 			})
 		})
 
 		Convey("When the 'maxAge' function is called and the API does not have the requested Cache Time resource", func() {
 			setMockResponseStatusCode(http.StatusNotFound)
-			result, isCalculated := maxAge(ctx, "/some-valid-url", cfg)
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
 
 			Convey("Then it should return a default cache time", func() {
-				So(result, ShouldEqual, defaultCacheTime)
-				So(isCalculated, ShouldBeFalse)
+				So(result.seconds, ShouldEqual, defaultCacheTime)
+				So(result.countdownTo, ShouldBeZeroValue)
 			})
 		})
 
 		Convey("When the 'maxAge' function is called and the requested Cache Time resource does not have a release time", func() {
 			setMockResponseBody(`{"_id": "7fadfea5c8372c59c0d20599ff95b42a", "path": "/some-valid-path"}`)
-			result, isCalculated := maxAge(ctx, "/some-valid-url", cfg)
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
 
 			Convey("Then it should return a default cache time", func() {
-				So(result, ShouldEqual, defaultCacheTime)
-				So(isCalculated, ShouldBeFalse)
+				So(result.seconds, ShouldEqual, defaultCacheTime)
+				So(result.countdownTo, ShouldBeZeroValue)
 			})
 		})
 
@@ -152,14 +142,14 @@ func TestMaxAgeInteractionWithLegacyCacheAPI(t *testing.T) {
 					secondsUntilRelease := time.Until(futureReleaseTime).Seconds()
 					So(secondsUntilRelease, ShouldBeLessThan, defaultCacheTime)
 					setMockResponseWithReleaseTime(futureReleaseTime)
-					result, isCalculated := maxAge(ctx, "/some-valid-url", cfg)
+					result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
 
 					Convey("Then it should return a calculated cache time", func() {
 						// Small error threshold (in seconds) to account for result discrepancies due to using an actual
 						// time (not mocked) and the tests possibly running slow
 						errorThreshold := 3
-						So(result, ShouldAlmostEqual, secondsUntilRelease, errorThreshold)
-						So(isCalculated, ShouldBeTrue)
+						So(result.seconds, ShouldAlmostEqual, secondsUntilRelease, errorThreshold)
+						So(result.countdownTo.Equal(futureReleaseTime.Truncate(time.Second)), ShouldBeTrue)
 					})
 				})
 
@@ -168,11 +158,37 @@ func TestMaxAgeInteractionWithLegacyCacheAPI(t *testing.T) {
 					secondsUntilRelease := time.Until(futureReleaseTime).Seconds()
 					So(secondsUntilRelease, ShouldBeGreaterThan, defaultCacheTime)
 					setMockResponseWithReleaseTime(futureReleaseTime)
-					result, isCalculated := maxAge(ctx, "/some-valid-url", cfg)
+					result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
 
 					Convey("Then it should return a default cache time", func() {
-						So(result, ShouldEqual, defaultCacheTime)
-						So(isCalculated, ShouldBeFalse)
+						So(result.seconds, ShouldEqual, defaultCacheTime)
+						So(result.countdownTo, ShouldBeZeroValue)
+					})
+				})
+			})
+
+			Convey("And the release time is in the future, but a shorter cache time is given", func() {
+				const notFoundCacheTime = 10
+
+				Convey("And the release will happen sooner than the given cache time", func() {
+					futureReleaseTime := time.Now().Add(5 * time.Second)
+					setMockResponseWithReleaseTime(futureReleaseTime)
+					result := maxAge(ctx, "/some-valid-url", notFoundCacheTime*time.Second, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
+
+					Convey("Then it should return a calculated cache time", func() {
+						So(result.seconds, ShouldBeLessThanOrEqualTo, 5)
+						So(result.countdownTo, ShouldNotBeZeroValue)
+					})
+				})
+
+				Convey("And the release will happen later than the given cache time", func() {
+					futureReleaseTime := time.Now().Add(30 * time.Second)
+					setMockResponseWithReleaseTime(futureReleaseTime)
+					result := maxAge(ctx, "/some-valid-url", notFoundCacheTime*time.Second, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
+
+					Convey("Then it should return the given cache time", func() {
+						So(result.seconds, ShouldEqual, notFoundCacheTime)
+						So(result.countdownTo, ShouldBeZeroValue)
 					})
 				})
 			})
@@ -183,11 +199,11 @@ func TestMaxAgeInteractionWithLegacyCacheAPI(t *testing.T) {
 					secondsSinceRelease := time.Since(pastReleaseTime).Seconds()
 					So(secondsSinceRelease, ShouldBeLessThan, publishExpiryOffset)
 					setMockResponseWithReleaseTime(pastReleaseTime)
-					result, isCalculated := maxAge(ctx, "/some-valid-url", cfg)
+					result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
 
 					Convey("Then it should return a short cache time", func() {
-						So(result, ShouldEqual, shortCacheTime)
-						So(isCalculated, ShouldBeFalse)
+						So(result.seconds, ShouldEqual, shortCacheTime)
+						So(result.countdownTo, ShouldBeZeroValue)
 					})
 				})
 
@@ -196,11 +212,11 @@ func TestMaxAgeInteractionWithLegacyCacheAPI(t *testing.T) {
 					secondsSinceRelease := time.Since(pastReleaseTime).Seconds()
 					So(secondsSinceRelease, ShouldBeGreaterThan, publishExpiryOffset)
 					setMockResponseWithReleaseTime(pastReleaseTime)
-					result, isCalculated := maxAge(ctx, "/some-valid-url", cfg)
+					result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
 
 					Convey("Then it should return a default cache time", func() {
-						So(result, ShouldEqual, defaultCacheTime)
-						So(isCalculated, ShouldBeFalse)
+						So(result.seconds, ShouldEqual, defaultCacheTime)
+						So(result.countdownTo, ShouldBeZeroValue)
 					})
 				})
 			})
@@ -242,23 +258,105 @@ func TestMaxAgeWithPublishExpiryOffset(t *testing.T) {
 
 			Convey("When the Publish Expiry Offset is toggled ON and the 'maxAge' function is called", func() {
 				cfg.EnablePublishExpiryOffset = true
-				result, isCalculated := maxAge(ctx, "/some-valid-url", cfg)
+				result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
 
 				Convey("Then it should return a short cache time", func() {
-					So(result, ShouldEqual, shortCacheTime)
-					So(isCalculated, ShouldBeFalse)
+					So(result.seconds, ShouldEqual, shortCacheTime)
+					So(result.countdownTo, ShouldBeZeroValue)
 				})
 			})
 
 			Convey("When the Publish Expiry Offset is toggled OFF and the 'maxAge' function is called", func() {
 				cfg.EnablePublishExpiryOffset = false
-				result, isCalculated := maxAge(ctx, "/some-valid-url", cfg)
+				result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(cfg)))
 
 				Convey("Then it should return a default cache time", func() {
-					So(result, ShouldEqual, defaultCacheTime)
-					So(isCalculated, ShouldBeFalse)
+					So(result.seconds, ShouldEqual, defaultCacheTime)
+					So(result.countdownTo, ShouldBeZeroValue)
 				})
 			})
 		})
 	})
 }
+
+func TestMaxAgeWithReleaseTimeSource(t *testing.T) {
+	Convey("Given a release time source and some pre-configured cache time values", t, func() {
+		ctx := context.Background()
+		cfg := &config.Config{
+			CacheTimeDefault: 100 * time.Second,
+			CacheTimeErrored: 50 * time.Second,
+		}
+
+		Convey("When the source knows the release time of the page and it will happen very soon", func() {
+			source := &stubReleaseTimeSource{releaseTime: time.Now().Add(30 * time.Second), statusCode: http.StatusOK}
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, source)
+
+			Convey("Then the max-age counts down to the release time", func() {
+				So(result.seconds, ShouldBeBetweenOrEqual, 28, 30)
+				So(result.countdownTo, ShouldNotBeZeroValue)
+				So(source.calls, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the source fails", func() {
+			source := &stubReleaseTimeSource{err: errors.New("source error")}
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, time.Now(), cfg, source)
+
+			Convey("Then it should return an errored cache time, as the release time is unknown", func() {
+				So(result.seconds, ShouldEqual, 50)
+				So(result.countdownTo, ShouldBeZeroValue)
+				So(result.releaseTimeUnknown, ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestMaxAgeAroundTheReleaseMoment(t *testing.T) {
+	Convey("Given a page that is released at 09:30 and some pre-configured cache time values", t, func() {
+		ctx := context.Background()
+		releaseTime := time.Date(2024, time.February, 15, 9, 30, 0, 0, time.UTC)
+		source := &stubReleaseTimeSource{releaseTime: releaseTime, statusCode: http.StatusOK}
+		cfg := &config.Config{
+			CacheTimeDefault:          100 * time.Second,
+			CacheTimeShort:            10 * time.Second,
+			EnablePublishExpiryOffset: true,
+			PublishExpiryOffset:       time.Minute,
+		}
+
+		Convey("When the max-age is calculated at 09:29:30", func() {
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, releaseTime.Add(-30*time.Second), cfg, source)
+
+			Convey("Then it should count down to the release exactly", func() {
+				So(result.seconds, ShouldEqual, 30)
+				So(result.countdownTo, ShouldEqual, releaseTime)
+			})
+		})
+
+		Convey("When the max-age is calculated one second before the release", func() {
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, releaseTime.Add(-time.Second), cfg, source)
+
+			Convey("Then it should be one second", func() {
+				So(result.seconds, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the max-age is calculated at the release time", func() {
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, releaseTime, cfg, source)
+
+			Convey("Then it should be the short cache time, as the page was released recently", func() {
+				So(result.seconds, ShouldEqual, 10)
+				So(result.countdownTo, ShouldBeZeroValue)
+				So(result.releaseTime, ShouldEqual, releaseTime)
+			})
+		})
+
+		Convey("When the max-age is calculated after the publish expiry offset", func() {
+			result := maxAge(ctx, "/some-valid-url", cfg.CacheTimeDefault, releaseTime.Add(time.Minute), cfg, source)
+
+			Convey("Then it should be the default cache time", func() {
+				So(result.seconds, ShouldEqual, 100)
+				So(result.releaseTime, ShouldBeZeroValue)
+			})
+		})
+	})
+}
@@ -0,0 +1,205 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/pkg/errors"
+)
+
+// CacheTimes represents a page of the Cache Time resources listed by the Legacy Cache API
+type CacheTimes struct {
+	Items      []CacheTimeItem `json:"items"`
+	Count      int             `json:"count"`
+	Offset     int             `json:"offset"`
+	Limit      int             `json:"limit"`
+	TotalCount int             `json:"total_count"`
+}
+
+// CacheTimeItem represents a single Cache Time resource listed by the Legacy Cache API
+type CacheTimeItem struct {
+	Path        string     `json:"path"`
+	ReleaseTime *time.Time `json:"release_time"`
+}
+
+// ReleaseSchedule is a ReleaseTimeSource holding the release times of the pages that have been published recently or
+// will be published soon. It is loaded in the background, by regularly listing the Cache Time resources of the Legacy
+// Cache API (or any other service implementing the same endpoint) with a release time within the window, so that the
+// release times of these pages are known without any call to the Legacy Cache API.
+type ReleaseSchedule struct {
+	mutex        sync.RWMutex
+	cfg          config.ReleaseSchedule
+	client       *http.Client
+	now          func() time.Time
+	releaseTimes map[string]time.Time
+	lastLoaded   time.Time
+	stop         chan struct{}
+	stopped      sync.WaitGroup
+}
+
+// NewReleaseSchedule creates an empty ReleaseSchedule, which is not loaded until it is started
+func NewReleaseSchedule(cfg *config.Config) *ReleaseSchedule {
+	return &ReleaseSchedule{
+		cfg:          cfg.ReleaseSchedule,
+		client:       newLegacyCacheAPIClient(cfg),
+		now:          time.Now,
+		releaseTimes: make(map[string]time.Time),
+		stop:         make(chan struct{}),
+	}
+}
+
+// Start loads the schedule straight away and then at every poll interval, until the schedule is stopped
+func (s *ReleaseSchedule) Start(ctx context.Context) {
+	s.stopped.Add(1)
+
+	go func() {
+		defer s.stopped.Done()
+
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.Load(ctx); err != nil {
+				log.Error(ctx, "error loading the release schedule", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops loading the schedule and waits for any load in progress to finish
+func (s *ReleaseSchedule) Stop() {
+	close(s.stop)
+	s.stopped.Wait()
+}
+
+// Load replaces the schedule with the release times that are currently within the window
+func (s *ReleaseSchedule) Load(ctx context.Context) error {
+	now := s.now()
+	from, to := now.Add(-s.cfg.LookBehind), now.Add(s.cfg.LookAhead)
+
+	releaseTimes := make(map[string]time.Time)
+	for offset := 0; ; {
+		cacheTimes, err := s.list(ctx, from, to, offset)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range cacheTimes.Items {
+			if item.ReleaseTime != nil {
+				releaseTimes[item.Path] = *item.ReleaseTime
+			}
+		}
+
+		offset += cacheTimes.Count
+		if cacheTimes.Count == 0 || offset >= cacheTimes.TotalCount {
+			break
+		}
+	}
+
+	s.mutex.Lock()
+	s.releaseTimes = releaseTimes
+	s.lastLoaded = now
+	s.mutex.Unlock()
+
+	log.Info(ctx, "loaded the release schedule", log.Data{"release_times": len(releaseTimes), "from": from, "to": to})
+
+	return nil
+}
+
+func (s *ReleaseSchedule) list(ctx context.Context, from, to time.Time, offset int) (CacheTimes, error) {
+	query := url.Values{}
+	query.Set("release_time_from", from.UTC().Format(time.RFC3339))
+	query.Set("release_time_to", to.UTC().Format(time.RFC3339))
+	query.Set("offset", strconv.Itoa(offset))
+	query.Set("limit", strconv.Itoa(s.cfg.PageSize))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL+"/v1/cache-times?"+query.Encode(), http.NoBody) //nolint:gosec // we control the URLs so not technically as tainted as it suggests
+	if err != nil {
+		return CacheTimes{}, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return CacheTimes{}, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return CacheTimes{}, fmt.Errorf("unexpected status code listing the cache times: %d", resp.StatusCode)
+	}
+
+	var cacheTimes CacheTimes
+	if err = json.NewDecoder(resp.Body).Decode(&cacheTimes); err != nil {
+		return CacheTimes{}, errors.Wrap(err, "unable to decode the cache times")
+	}
+
+	return cacheTimes, nil
+}
+
+// GetReleaseTime returns the release time of the given page path if it is in the schedule, otherwise the page is not
+// found
+func (s *ReleaseSchedule) GetReleaseTime(_ context.Context, path string) (time.Time, int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	releaseTime, ok := s.releaseTimes[path]
+	if !ok {
+		return time.Time{}, http.StatusNotFound, nil
+	}
+
+	return releaseTime, http.StatusOK, nil
+}
+
+// Evict removes the release time of the page at the given URI from the schedule, until the schedule is next loaded
+func (s *ReleaseSchedule) Evict(ctx context.Context, uri string) error {
+	pagePath, err := getPagePath(ctx, uri)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	delete(s.releaseTimes, pagePath)
+	s.mutex.Unlock()
+
+	return nil
+}
+
+// Checker reports the state of the schedule to the health check. The schedule is reported as a warning if it has not
+// been loaded successfully for longer than the stale period, as the proxy falls back to the other release time sources.
+func (s *ReleaseSchedule) Checker(_ context.Context, state *healthcheck.CheckState) error {
+	s.mutex.RLock()
+	lastLoaded, releaseTimes := s.lastLoaded, len(s.releaseTimes)
+	s.mutex.RUnlock()
+
+	if lastLoaded.IsZero() {
+		return state.Update(healthcheck.StatusWarning, "release schedule has not been loaded yet", 0)
+	}
+
+	lastLoadedMessage := lastLoaded.UTC().Format(time.RFC3339)
+	if s.now().Sub(lastLoaded) > s.cfg.StaleAfter {
+		return state.Update(healthcheck.StatusWarning, "release schedule is stale, last loaded at "+lastLoadedMessage, 0)
+	}
+
+	return state.Update(healthcheck.StatusOK, fmt.Sprintf("%d release times loaded at %s", releaseTimes, lastLoadedMessage), 0)
+}
@@ -0,0 +1,143 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReleaseSchedule(t *testing.T) {
+	Convey("Given a Legacy Cache API listing three cache times over two pages and a release schedule", t, func() {
+		ctx := context.Background()
+		now := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+		upcomingRelease, recentRelease := now.Add(time.Hour), now.Add(-time.Hour)
+		items := []CacheTimeItem{
+			{Path: "/upcoming", ReleaseTime: &upcomingRelease},
+			{Path: "/recent", ReleaseTime: &recentRelease},
+			{Path: "/without-release-time"},
+		}
+
+		var queries []string
+		statusCode := http.StatusOK
+		mockLegacyCacheAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			queries = append(queries, r.URL.RawQuery)
+			if r.URL.Path != "/v1/cache-times" || statusCode != http.StatusOK {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			page := items[offset:min(offset+limit, len(items))]
+			_ = json.NewEncoder(w).Encode(CacheTimes{Items: page, Count: len(page), Offset: offset, Limit: limit, TotalCount: len(items)})
+		}))
+		defer mockLegacyCacheAPI.Close()
+
+		cfg := &config.Config{
+			ReleaseSchedule: config.ReleaseSchedule{
+				URL:          mockLegacyCacheAPI.URL,
+				PollInterval: time.Minute,
+				LookAhead:    24 * time.Hour,
+				LookBehind:   time.Hour,
+				StaleAfter:   5 * time.Minute,
+				PageSize:     2,
+			},
+		}
+		schedule := NewReleaseSchedule(cfg)
+		schedule.now = func() time.Time { return now }
+		state := healthcheck.NewCheckState("release schedule")
+
+		Convey("When it has not been loaded yet", func() {
+			_, status, err := schedule.GetReleaseTime(ctx, "/upcoming")
+			_ = schedule.Checker(ctx, state)
+
+			Convey("Then no page is found and the health check reports a warning", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusNotFound)
+				So(state.Status(), ShouldEqual, healthcheck.StatusWarning)
+				So(state.Message(), ShouldEqual, "release schedule has not been loaded yet")
+			})
+		})
+
+		Convey("When it is loaded", func() {
+			err := schedule.Load(ctx)
+			So(err, ShouldBeNil)
+
+			Convey("Then every page of cache times within the window is requested", func() {
+				So(queries, ShouldResemble, []string{
+					"limit=2&offset=0&release_time_from=2024-01-31T08%3A00%3A00Z&release_time_to=2024-02-01T09%3A00%3A00Z",
+					"limit=2&offset=2&release_time_from=2024-01-31T08%3A00%3A00Z&release_time_to=2024-02-01T09%3A00%3A00Z",
+				})
+			})
+
+			Convey("Then the release times of the pages in the schedule are returned", func() {
+				releaseTime, status, err := schedule.GetReleaseTime(ctx, "/upcoming")
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(releaseTime, ShouldEqual, upcomingRelease)
+
+				releaseTime, status, err = schedule.GetReleaseTime(ctx, "/recent")
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(releaseTime, ShouldEqual, recentRelease)
+			})
+
+			Convey("Then the pages without a release time or not in the schedule are not found", func() {
+				_, status, err := schedule.GetReleaseTime(ctx, "/without-release-time")
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusNotFound)
+
+				_, status, err = schedule.GetReleaseTime(ctx, "/unknown")
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusNotFound)
+			})
+
+			Convey("Then a page that is evicted is no longer in the schedule", func() {
+				So(schedule.Evict(ctx, "/upcoming"), ShouldBeNil)
+				_, status, err := schedule.GetReleaseTime(ctx, "/upcoming")
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusNotFound)
+			})
+
+			Convey("Then the health check reports the schedule as ok", func() {
+				_ = schedule.Checker(ctx, state)
+				So(state.Status(), ShouldEqual, healthcheck.StatusOK)
+				So(state.Message(), ShouldEqual, "2 release times loaded at 2024-01-31T09:00:00Z")
+			})
+
+			Convey("And the next loads fail for longer than the stale period", func() {
+				statusCode = http.StatusInternalServerError
+				now = now.Add(6 * time.Minute)
+				err := schedule.Load(ctx)
+
+				Convey("Then the previous schedule is kept and the health check reports it as stale", func() {
+					So(err, ShouldNotBeNil)
+					_, status, _ := schedule.GetReleaseTime(ctx, "/upcoming")
+					So(status, ShouldEqual, http.StatusOK)
+
+					_ = schedule.Checker(ctx, state)
+					So(state.Status(), ShouldEqual, healthcheck.StatusWarning)
+					So(state.Message(), ShouldEqual, "release schedule is stale, last loaded at 2024-01-31T09:00:00Z")
+				})
+			})
+		})
+
+		Convey("When it is started and stopped", func() {
+			schedule.Start(ctx)
+			So(func() { schedule.Stop() }, ShouldNotPanic)
+
+			Convey("Then it has been loaded straight away", func() {
+				_, status, _ := schedule.GetReleaseTime(ctx, "/upcoming")
+				So(status, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+}
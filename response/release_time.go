@@ -1,24 +1,46 @@
 package response
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type CacheTime struct {
 	ReleaseTime *time.Time `json:"release_time"`
 }
 
-func getReleaseTime(path, legacyCacheAPIURL string) (time.Time, int, error) {
-	pathHash := md5.Sum([]byte(path))
-	cacheTimeID := hex.EncodeToString(pathHash[:])
-	cacheTimeResourceURL := legacyCacheAPIURL + "/v1/cache-times/" + cacheTimeID
+// LegacyCacheAPISource is a ReleaseTimeSource that gets the release times from the Cache Time resources of the Legacy
+// Cache API
+type LegacyCacheAPISource struct {
+	client *http.Client
+	url    string
+}
+
+// NewLegacyCacheAPISource creates a LegacyCacheAPISource for the Legacy Cache API in the configuration
+func NewLegacyCacheAPISource(cfg *config.Config) *LegacyCacheAPISource {
+	return &LegacyCacheAPISource{
+		client: newLegacyCacheAPIClient(cfg),
+		url:    cfg.LegacyCacheAPIURL,
+	}
+}
+
+// GetReleaseTime returns the release time of the Cache Time resource of the given page path
+func (s *LegacyCacheAPISource) GetReleaseTime(ctx context.Context, path string) (time.Time, int, error) {
+	return getReleaseTime(ctx, s.client, path, s.url)
+}
+
+func getReleaseTime(ctx context.Context, client *http.Client, path, legacyCacheAPIURL string) (time.Time, int, error) {
+	cacheTimeResourceURL := legacyCacheAPIURL + "/v1/cache-times/" + cacheTimeID(path)
 
-	cacheTimeResource, statusCode, err := fetchCacheTimeResource(cacheTimeResourceURL)
+	cacheTimeResource, statusCode, err := fetchCacheTimeResource(ctx, client, cacheTimeResourceURL)
 	if err != nil {
 		return time.Time{}, 0, err
 	}
@@ -33,13 +55,33 @@ func getReleaseTime(path, legacyCacheAPIURL string) (time.Time, int, error) {
 	return releaseTime, statusCode, nil
 }
 
-func fetchCacheTimeResource(cacheTimeResourceURL string) (CacheTime, int, error) {
-	req, err := http.NewRequest(http.MethodGet, cacheTimeResourceURL, http.NoBody) //nolint:gosec // we control the URLs so not technically as tainted as it suggests
+// cacheTimeID returns the ID of the Cache Time resource of the given page path
+func cacheTimeID(path string) string {
+	pathHash := md5.Sum([]byte(path))
+	return hex.EncodeToString(pathHash[:])
+}
+
+// newLegacyCacheAPIClient creates the HTTP client used to call the Legacy Cache API. The calls are traced when Open
+// Telemetry is enabled.
+func newLegacyCacheAPIClient(cfg *config.Config) *http.Client {
+	var transport http.RoundTripper = http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.OtelEnabled {
+		transport = otelhttp.NewTransport(transport)
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.LegacyCacheAPITimeout,
+	}
+}
+
+func fetchCacheTimeResource(ctx context.Context, client *http.Client, cacheTimeResourceURL string) (CacheTime, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cacheTimeResourceURL, http.NoBody) //nolint:gosec // we control the URLs so not technically as tainted as it suggests
 	if err != nil {
 		return CacheTime{}, 0, err
 	}
 
-	resp, err := http.DefaultClient.Do(req) //nolint:gosec // we control the URLs so not technically as tainted as it suggests
+	resp, err := client.Do(req) //nolint:gosec // we control the URLs so not technically as tainted as it suggests
 	if err != nil {
 		return CacheTime{}, 0, err
 	}
@@ -0,0 +1,248 @@
+package response
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/pkg/errors"
+)
+
+// ReleaseTimeCache is a ReleaseTimeSource that keeps the release times returned by another source (usually the Legacy
+// Cache API) in a bounded, in-memory cache, keyed by Cache Time ID. Once it holds the maximum number of entries, the
+// least recently used one is evicted. Found release times are cached for the TTL, but never past the release time
+// itself, so that a new release time set when the page is published is picked up straight away. Release times that
+// were not found are cached for the negative TTL. Any other response from the source is not cached.
+//
+// Concurrent lookups of the same page share a single call to the source, so that a burst of requests for a page (e.g.
+// when it is released) does not turn into a burst of calls. While the source is unavailable (see
+// GuardedReleaseTimeSource), the last known release time of a page is returned, even if it has expired.
+type ReleaseTimeCache struct {
+	mutex       sync.Mutex
+	maxEntries  int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+	source      ReleaseTimeSource
+	entries     map[string]*list.Element
+	lru         *list.List
+	calls       map[string]*releaseTimeCall
+	hits        atomic.Uint64
+	staleHits   atomic.Uint64
+	misses      atomic.Uint64
+}
+
+// releaseTimeCall is a call to the source that is in flight. Its results are set before done is closed. The results of
+// an evicted call are not cached, as they may be out of date (evicted is guarded by the mutex of the cache).
+type releaseTimeCall struct {
+	done        chan struct{}
+	releaseTime time.Time
+	statusCode  int
+	err         error
+	evicted     bool
+}
+
+type releaseTimeCacheEntry struct {
+	cacheTimeID string
+	releaseTime time.Time
+	statusCode  int
+	expiresAt   time.Time
+}
+
+// ReleaseTimeCacheStats is a snapshot of the usage of a ReleaseTimeCache. Stale hits are the expired entries returned
+// while the source was unavailable.
+type ReleaseTimeCacheStats struct {
+	Entries   int    `json:"entries"`
+	Hits      uint64 `json:"hits"`
+	StaleHits uint64 `json:"stale_hits"`
+	Misses    uint64 `json:"misses"`
+}
+
+// NewReleaseTimeCache creates an empty ReleaseTimeCache. The cache is disabled if the maximum number of entries is not
+// positive.
+func NewReleaseTimeCache(cfg *config.Config, source ReleaseTimeSource) *ReleaseTimeCache {
+	return &ReleaseTimeCache{
+		maxEntries:  cfg.ReleaseTimeCacheMaxEntries,
+		ttl:         cfg.ReleaseTimeCacheTTL,
+		negativeTTL: cfg.ReleaseTimeCacheNegativeTTL,
+		now:         time.Now,
+		source:      source,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		calls:       make(map[string]*releaseTimeCall),
+	}
+}
+
+// GetReleaseTime returns the release time of the given page path, from the cache if possible, otherwise from the
+// source. If the context is done before the source responds, the context's error is returned, but
+// the call carries on for any other lookups of the same page.
+//
+// While the source is unavailable, the last known release time of the page is returned, even if it has expired. If
+// there is none, ErrReleaseTimeSourceUnavailable is returned.
+func (c *ReleaseTimeCache) GetReleaseTime(ctx context.Context, path string) (time.Time, int, error) {
+	id := cacheTimeID(path)
+
+	entry, isCached := c.get(id)
+	if isCached && c.now().Before(entry.expiresAt) {
+		c.hits.Add(1)
+		return entry.releaseTime, entry.statusCode, nil
+	}
+
+	call := c.call(ctx, id, path)
+
+	if c.maxEntries > 0 {
+		c.misses.Add(1)
+	}
+
+	select {
+	case <-call.done:
+		if isCached && errors.Is(call.err, ErrReleaseTimeSourceUnavailable) {
+			c.staleHits.Add(1)
+			return entry.releaseTime, entry.statusCode, nil
+		}
+		return call.releaseTime, call.statusCode, call.err
+	case <-ctx.Done():
+		return time.Time{}, 0, ctx.Err()
+	}
+}
+
+// call returns the in-flight call to the source for the given Cache Time ID, starting one if there is none.
+// A new call keeps the values of the given context (so that it is part of the same trace), but is not cancelled with
+// it, as other lookups may be waiting for its result: it is bounded by the timeout of the client instead.
+func (c *ReleaseTimeCache) call(ctx context.Context, id, path string) *releaseTimeCall {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if call, ok := c.calls[id]; ok {
+		return call
+	}
+
+	call := &releaseTimeCall{done: make(chan struct{})}
+	c.calls[id] = call
+	callCtx := context.WithoutCancel(ctx)
+
+	go func() {
+		call.releaseTime, call.statusCode, call.err = c.source.GetReleaseTime(callCtx, path)
+
+		if call.err == nil && c.maxEntries > 0 {
+			c.set(id, call)
+		}
+
+		c.mutex.Lock()
+		if c.calls[id] == call {
+			delete(c.calls, id)
+		}
+		c.mutex.Unlock()
+
+		close(call.done)
+	}()
+
+	return call
+}
+
+// get returns the cached entry for the given Cache Time ID, even if it has expired
+func (c *ReleaseTimeCache) get(id string) (releaseTimeCacheEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[id]
+	if !ok {
+		return releaseTimeCacheEntry{}, false
+	}
+
+	c.lru.MoveToFront(element)
+
+	return *element.Value.(*releaseTimeCacheEntry), true
+}
+
+func (c *ReleaseTimeCache) set(id string, call *releaseTimeCall) {
+	now := c.now()
+	releaseTime, statusCode := call.releaseTime, call.statusCode
+
+	var expiresAt time.Time
+	switch statusCode {
+	case http.StatusOK:
+		expiresAt = now.Add(c.ttl)
+		if releaseTime.After(now) && releaseTime.Before(expiresAt) {
+			expiresAt = releaseTime
+		}
+	case http.StatusNotFound:
+		expiresAt = now.Add(c.negativeTTL)
+	default:
+		return
+	}
+
+	if !expiresAt.After(now) {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if call.evicted {
+		return
+	}
+
+	if element, ok := c.entries[id]; ok {
+		c.remove(element)
+	}
+
+	c.entries[id] = c.lru.PushFront(&releaseTimeCacheEntry{
+		cacheTimeID: id,
+		releaseTime: releaseTime,
+		statusCode:  statusCode,
+		expiresAt:   expiresAt,
+	})
+
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Evict removes the release time of the page at the given URI from the cache, so that it is looked up again the next
+// time the page is requested. The result of any call to the source for the page that is in flight is not cached.
+func (c *ReleaseTimeCache) Evict(ctx context.Context, uri string) error {
+	pagePath, err := getPagePath(ctx, uri)
+	if err != nil {
+		return err
+	}
+	id := cacheTimeID(pagePath)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[id]; ok {
+		c.remove(element)
+	}
+
+	if call, ok := c.calls[id]; ok {
+		call.evicted = true
+		delete(c.calls, id)
+	}
+
+	return nil
+}
+
+// remove must be called with the mutex locked
+func (c *ReleaseTimeCache) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*releaseTimeCacheEntry).cacheTimeID)
+}
+
+// Stats returns the number of entries in the cache and the number of hits and misses since it was created
+func (c *ReleaseTimeCache) Stats() ReleaseTimeCacheStats {
+	c.mutex.Lock()
+	entries := c.lru.Len()
+	c.mutex.Unlock()
+
+	return ReleaseTimeCacheStats{
+		Entries:   entries,
+		Hits:      c.hits.Load(),
+		StaleHits: c.staleHits.Load(),
+		Misses:    c.misses.Load(),
+	}
+}
@@ -0,0 +1,319 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/circuitbreaker"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReleaseTimeCache(t *testing.T) {
+	Convey("Given a Legacy Cache API and a release time cache", t, func() {
+		ctx := context.Background()
+		now := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+		var requestCount atomic.Int32
+		var writeMockResponse func(w http.ResponseWriter) error
+		mockLegacyCacheAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requestCount.Add(1)
+			if err := writeMockResponse(w); err != nil {
+				t.Fatal("error setting the mock server response body", err)
+			}
+		}))
+		defer mockLegacyCacheAPI.Close()
+
+		cfg := &config.Config{
+			ReleaseTimeCacheMaxEntries:  2,
+			ReleaseTimeCacheTTL:         time.Minute,
+			ReleaseTimeCacheNegativeTTL: 10 * time.Second,
+		}
+		source := NewGuardedReleaseTimeSource(ReleaseTimeSourceLegacyCacheAPI, NewLegacyCacheAPISource(&config.Config{LegacyCacheAPIURL: mockLegacyCacheAPI.URL}), cfg.LegacyCacheAPICircuitBreaker)
+		releaseTimes := NewReleaseTimeCache(cfg, source)
+		releaseTimes.now = func() time.Time { return now }
+
+		setMockReleaseTime := func(releaseTime time.Time) {
+			writeMockResponse = setMockResponse(fmt.Sprintf(`{"release_time": %q}`, releaseTime.Format(time.RFC3339)), http.StatusOK)
+		}
+
+		Convey("When the release time of a page is requested twice within the TTL", func() {
+			releaseTime := now.Add(-time.Hour)
+			setMockReleaseTime(releaseTime)
+			_, _, err := releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+			So(err, ShouldBeNil)
+			now = now.Add(59 * time.Second)
+			cachedReleaseTime, statusCode, err := releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+
+			Convey("Then the second one is returned from the cache", func() {
+				So(err, ShouldBeNil)
+				So(cachedReleaseTime, ShouldEqual, releaseTime)
+				So(statusCode, ShouldEqual, http.StatusOK)
+				So(requestCount.Load(), ShouldEqual, 1)
+				So(releaseTimes.Stats(), ShouldResemble, ReleaseTimeCacheStats{Entries: 1, Hits: 1, Misses: 1})
+			})
+		})
+
+		Convey("When the release time of a page is requested again after the TTL", func() {
+			setMockReleaseTime(now.Add(-time.Hour))
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+			now = now.Add(time.Minute)
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+
+			Convey("Then it is requested from the Legacy Cache API again", func() {
+				So(requestCount.Load(), ShouldEqual, 2)
+				So(releaseTimes.Stats(), ShouldResemble, ReleaseTimeCacheStats{Entries: 1, Hits: 0, Misses: 2})
+			})
+		})
+
+		Convey("When the release time of a page is before the end of the TTL", func() {
+			setMockReleaseTime(now.Add(30 * time.Second))
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+
+			Convey("Then it is cached until the release time", func() {
+				now = now.Add(29 * time.Second)
+				_, _, _ = releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+				So(requestCount.Load(), ShouldEqual, 1)
+
+				now = now.Add(time.Second)
+				_, _, _ = releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+				So(requestCount.Load(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When a Cache Time resource is not found", func() {
+			writeMockResponse = setMockResponse("", http.StatusNotFound)
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+
+			Convey("Then it is cached for the negative TTL", func() {
+				now = now.Add(9 * time.Second)
+				_, statusCode, err := releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+				So(err, ShouldBeNil)
+				So(statusCode, ShouldEqual, http.StatusNotFound)
+				So(requestCount.Load(), ShouldEqual, 1)
+
+				now = now.Add(time.Second)
+				_, _, _ = releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+				So(requestCount.Load(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When the Legacy Cache API returns an unexpected status code", func() {
+			writeMockResponse = setMockResponse("", http.StatusInternalServerError)
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+			_, statusCode, _ := releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+
+			Convey("Then the response is not cached", func() {
+				So(statusCode, ShouldEqual, http.StatusInternalServerError)
+				So(requestCount.Load(), ShouldEqual, 2)
+				So(releaseTimes.Stats().Entries, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When more pages than the maximum number of entries are requested", func() {
+			setMockReleaseTime(now.Add(-time.Hour))
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/first-path")
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/second-path")
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/first-path")
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/third-path")
+
+			Convey("Then the least recently used page is evicted", func() {
+				So(releaseTimes.Stats().Entries, ShouldEqual, 2)
+				_, _, _ = releaseTimes.GetReleaseTime(ctx, "/first-path")
+				So(requestCount.Load(), ShouldEqual, 3)
+				_, _, _ = releaseTimes.GetReleaseTime(ctx, "/second-path")
+				So(requestCount.Load(), ShouldEqual, 4)
+			})
+		})
+
+		Convey("When the release time of a cached page is evicted", func() {
+			setMockReleaseTime(now.Add(-time.Hour))
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/other-valid-path")
+			err := releaseTimes.Evict(ctx, "/some-valid-path/")
+
+			Convey("Then only that page is looked up again the next time it is requested", func() {
+				So(err, ShouldBeNil)
+				So(releaseTimes.Stats().Entries, ShouldEqual, 1)
+				newReleaseTime := now.Add(-time.Minute)
+				setMockReleaseTime(newReleaseTime)
+				releaseTime, _, _ := releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+				So(releaseTime, ShouldEqual, newReleaseTime)
+				So(requestCount.Load(), ShouldEqual, 3)
+				_, _, _ = releaseTimes.GetReleaseTime(ctx, "/other-valid-path")
+				So(requestCount.Load(), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When the cache is disabled", func() {
+			cfg.ReleaseTimeCacheMaxEntries = 0
+			disabledReleaseTimes := NewReleaseTimeCache(cfg, NewLegacyCacheAPISource(&config.Config{LegacyCacheAPIURL: mockLegacyCacheAPI.URL}))
+			setMockReleaseTime(now.Add(-time.Hour))
+			_, _, _ = disabledReleaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+			_, _, _ = disabledReleaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+
+			Convey("Then every release time is requested from the Legacy Cache API", func() {
+				So(requestCount.Load(), ShouldEqual, 2)
+				So(disabledReleaseTimes.Stats(), ShouldResemble, ReleaseTimeCacheStats{})
+			})
+		})
+	})
+}
+
+func TestReleaseTimeCacheCoalescing(t *testing.T) {
+	Convey("Given a Legacy Cache API that responds once it is released and a release time cache", t, func() {
+		ctx := context.Background()
+		releaseTime := time.Date(2024, time.January, 31, 9, 30, 0, 0, time.UTC)
+		var requestCount atomic.Int32
+		requestReceived := make(chan struct{}, 1)
+		releaseResponse := make(chan struct{})
+		mockLegacyCacheAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requestCount.Add(1)
+			requestReceived <- struct{}{}
+			<-releaseResponse
+			_, _ = fmt.Fprintf(w, `{"release_time": %q}`, releaseTime.Format(time.RFC3339))
+		}))
+		defer mockLegacyCacheAPI.Close()
+
+		Convey("When the release time of a page is requested concurrently", func() {
+			releaseTimes := NewReleaseTimeCache(&config.Config{ReleaseTimeCacheMaxEntries: 10, ReleaseTimeCacheTTL: time.Minute}, NewLegacyCacheAPISource(&config.Config{LegacyCacheAPIURL: mockLegacyCacheAPI.URL}))
+			const concurrentLookups = 20
+			results := make(chan time.Time, concurrentLookups)
+
+			for i := 0; i < concurrentLookups; i++ {
+				go func() {
+					result, _, err := releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+					if err != nil {
+						t.Error("unexpected error getting the release time", err)
+					}
+					results <- result
+				}()
+			}
+			<-requestReceived
+			// every lookup has missed the cache, so is waiting for the in-flight call
+			for releaseTimes.Stats().Misses < concurrentLookups {
+				time.Sleep(time.Millisecond)
+			}
+			close(releaseResponse)
+
+			Convey("Then they share a single call to the Legacy Cache API and all get its result", func() {
+				for i := 0; i < concurrentLookups; i++ {
+					So(<-results, ShouldEqual, releaseTime)
+				}
+				So(requestCount.Load(), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When a page is evicted while its release time is being looked up", func() {
+			releaseTimes := NewReleaseTimeCache(&config.Config{ReleaseTimeCacheMaxEntries: 10, ReleaseTimeCacheTTL: time.Minute}, NewLegacyCacheAPISource(&config.Config{LegacyCacheAPIURL: mockLegacyCacheAPI.URL}))
+			result := make(chan time.Time, 1)
+			go func() {
+				releaseTime, _, _ := releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+				result <- releaseTime
+			}()
+			<-requestReceived
+			err := releaseTimes.Evict(ctx, "/some-valid-path")
+			close(releaseResponse)
+
+			Convey("Then the lookup gets the result, but it is not cached", func() {
+				So(err, ShouldBeNil)
+				So(<-result, ShouldEqual, releaseTime)
+				So(releaseTimes.Stats().Entries, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the context of one of the lookups is cancelled", func() {
+			releaseTimes := NewReleaseTimeCache(&config.Config{ReleaseTimeCacheMaxEntries: 10, ReleaseTimeCacheTTL: time.Minute}, NewLegacyCacheAPISource(&config.Config{LegacyCacheAPIURL: mockLegacyCacheAPI.URL}))
+			cancellableCtx, cancel := context.WithCancel(ctx)
+			cancelledErr := make(chan error, 1)
+			go func() {
+				_, _, err := releaseTimes.GetReleaseTime(cancellableCtx, "/some-valid-path")
+				cancelledErr <- err
+			}()
+			<-requestReceived
+
+			otherResult := make(chan time.Time, 1)
+			go func() {
+				result, _, _ := releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+				otherResult <- result
+			}()
+			cancel()
+
+			Convey("Then that lookup gives up while the other one gets the result", func() {
+				So(<-cancelledErr, ShouldEqual, context.Canceled)
+				close(releaseResponse)
+				So(<-otherResult, ShouldEqual, releaseTime)
+				So(requestCount.Load(), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestReleaseTimeCacheCircuitBreaker(t *testing.T) {
+	Convey("Given a release time cache of a Legacy Cache API guarded by a circuit breaker", t, func() {
+		ctx := context.Background()
+		now := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+		releaseTime := now.Add(-time.Hour)
+		var requestCount atomic.Int32
+		statusCode := http.StatusOK
+		mockLegacyCacheAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requestCount.Add(1)
+			w.WriteHeader(statusCode)
+			_, _ = fmt.Fprintf(w, `{"release_time": %q}`, releaseTime.Format(time.RFC3339))
+		}))
+		defer mockLegacyCacheAPI.Close()
+
+		cfg := &config.Config{
+			ReleaseTimeCacheMaxEntries: 10,
+			ReleaseTimeCacheTTL:        time.Minute,
+			LegacyCacheAPICircuitBreaker: config.CircuitBreaker{
+				Enabled:      true,
+				FailureRatio: 0.5,
+				MinRequests:  1,
+				Window:       time.Minute,
+				OpenDuration: time.Hour,
+			},
+		}
+		source := NewGuardedReleaseTimeSource(ReleaseTimeSourceLegacyCacheAPI, NewLegacyCacheAPISource(&config.Config{LegacyCacheAPIURL: mockLegacyCacheAPI.URL}), cfg.LegacyCacheAPICircuitBreaker)
+		releaseTimes := NewReleaseTimeCache(cfg, source)
+		releaseTimes.now = func() time.Time { return now }
+
+		Convey("When the release time of a page is cached and the Legacy Cache API starts failing", func() {
+			_, _, err := releaseTimes.GetReleaseTime(ctx, "/cached-path")
+			So(err, ShouldBeNil)
+			now = now.Add(time.Minute)
+			statusCode = http.StatusInternalServerError
+			_, failedStatusCode, _ := releaseTimes.GetReleaseTime(ctx, "/cached-path")
+			So(failedStatusCode, ShouldEqual, http.StatusInternalServerError)
+			So(source.CircuitBreaker().State(), ShouldEqual, circuitbreaker.Open)
+
+			Convey("Then the expired release time is returned without calling the Legacy Cache API", func() {
+				staleReleaseTime, staleStatusCode, err := releaseTimes.GetReleaseTime(ctx, "/cached-path")
+				So(err, ShouldBeNil)
+				So(staleReleaseTime, ShouldEqual, releaseTime)
+				So(staleStatusCode, ShouldEqual, http.StatusOK)
+				So(requestCount.Load(), ShouldEqual, 2)
+				So(releaseTimes.Stats().StaleHits, ShouldEqual, 1)
+			})
+
+			Convey("Then an error is returned for a page that is not cached", func() {
+				_, _, err := releaseTimes.GetReleaseTime(ctx, "/uncached-path")
+				So(err, ShouldEqual, ErrReleaseTimeSourceUnavailable)
+				So(requestCount.Load(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When a Cache Time resource is not found", func() {
+			statusCode = http.StatusNotFound
+			_, _, _ = releaseTimes.GetReleaseTime(ctx, "/some-valid-path")
+
+			Convey("Then the circuit stays closed", func() {
+				So(source.CircuitBreaker().State(), ShouldEqual, circuitbreaker.Closed)
+			})
+		})
+	})
+}
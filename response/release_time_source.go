@@ -0,0 +1,187 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/circuitbreaker"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the sources of release times that can be configured in RELEASE_TIME_SOURCES
+const (
+	ReleaseTimeSourceFile           = "file"
+	ReleaseTimeSourceLegacyCacheAPI = "legacy-cache-api"
+)
+
+// ErrReleaseTimeSourceUnavailable is returned when a release time cannot be looked up because the circuit of the
+// source is open
+var ErrReleaseTimeSourceUnavailable = errors.New("the release time source is unavailable")
+
+//go:generate moq -out mock/release_time_source.go -pkg mock . ReleaseTimeSource
+
+// ReleaseTimeSource provides the release time of a page, along with a status code: http.StatusOK if the page is known
+// (its release time is zero if it has none) or http.StatusNotFound if it is not
+type ReleaseTimeSource interface {
+	GetReleaseTime(ctx context.Context, path string) (time.Time, int, error)
+}
+
+// NewReleaseTimeSource creates the chain of sources configured in RELEASE_TIME_SOURCES
+func NewReleaseTimeSource(cfg *config.Config) (ReleaseTimeSource, error) {
+	if len(cfg.ReleaseTimeSources) == 0 {
+		return nil, errors.New("at least one release time source is required")
+	}
+
+	sources := make([]ReleaseTimeSource, 0, len(cfg.ReleaseTimeSources))
+	for _, name := range cfg.ReleaseTimeSources {
+		switch name {
+		case ReleaseTimeSourceFile:
+			fileSource, err := NewFileReleaseTimeSource(cfg.ReleaseTimeFile)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, fileSource)
+		case ReleaseTimeSourceLegacyCacheAPI:
+			sources = append(sources, NewGuardedReleaseTimeSource(name, NewLegacyCacheAPISource(cfg), cfg.LegacyCacheAPICircuitBreaker))
+		default:
+			return nil, fmt.Errorf("unknown release time source %q", name)
+		}
+	}
+
+	if len(sources) == 1 {
+		return sources[0], nil
+	}
+
+	return ChainedReleaseTimeSource(sources), nil
+}
+
+// ReleaseTimeFile represents the contents of the file pointed at by RELEASE_TIME_FILE, which can be either YAML or JSON.
+// A page with a null release time is known, but has no release time.
+type ReleaseTimeFile struct {
+	ReleaseTimes map[string]*time.Time `yaml:"release_times"`
+}
+
+// FileReleaseTimeSource is a ReleaseTimeSource that gets the release times from a static file, for local development
+type FileReleaseTimeSource struct {
+	releaseTimes map[string]*time.Time
+}
+
+// NewFileReleaseTimeSource creates a FileReleaseTimeSource from the release times in the given file
+func NewFileReleaseTimeSource(path string) (*FileReleaseTimeSource, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the release time file")
+	}
+
+	var file ReleaseTimeFile
+	if err = yaml.Unmarshal(contents, &file); err != nil {
+		return nil, errors.Wrap(err, "unable to parse the release time file")
+	}
+
+	return &FileReleaseTimeSource{releaseTimes: file.ReleaseTimes}, nil
+}
+
+// GetReleaseTime returns the release time of the given page path from the file
+func (s *FileReleaseTimeSource) GetReleaseTime(_ context.Context, path string) (time.Time, int, error) {
+	releaseTime, ok := s.releaseTimes[path]
+	if !ok {
+		return time.Time{}, http.StatusNotFound, nil
+	}
+
+	if releaseTime == nil {
+		return time.Time{}, http.StatusOK, nil
+	}
+
+	return *releaseTime, http.StatusOK, nil
+}
+
+// ChainedReleaseTimeSource is a ReleaseTimeSource that tries each of its sources in turn, until one of them knows the
+// page. If none of them does, the page is not found. If a source fails, the next one is tried, and the error of the
+// last source to fail is returned if none of the others knows the page.
+type ChainedReleaseTimeSource []ReleaseTimeSource
+
+// GetReleaseTime returns the release time of the given page path from the first source that knows the page
+func (c ChainedReleaseTimeSource) GetReleaseTime(ctx context.Context, path string) (time.Time, int, error) {
+	statusCode := http.StatusNotFound
+	var lastErr error
+
+	for _, source := range c {
+		releaseTime, sourceStatusCode, err := source.GetReleaseTime(ctx, path)
+		switch {
+		case err != nil:
+			lastErr = err
+		case sourceStatusCode == http.StatusNotFound:
+			continue
+		case sourceStatusCode == http.StatusOK:
+			return releaseTime, sourceStatusCode, nil
+		default:
+			statusCode = sourceStatusCode
+		}
+	}
+
+	if lastErr != nil {
+		return time.Time{}, 0, lastErr
+	}
+
+	return time.Time{}, statusCode, nil
+}
+
+// GuardedReleaseTimeSource is a ReleaseTimeSource that guards a remote source (such as the Legacy Cache API) with a
+// circuit breaker. While the circuit is open, ErrReleaseTimeSourceUnavailable is returned without calling the source.
+// Local sources, such as the file, cannot fail in the same way, so they are not guarded.
+type GuardedReleaseTimeSource struct {
+	source         ReleaseTimeSource
+	circuitBreaker *circuitbreaker.CircuitBreaker
+}
+
+// NewGuardedReleaseTimeSource guards the given source with a circuit breaker of the given name
+func NewGuardedReleaseTimeSource(name string, source ReleaseTimeSource, cfg config.CircuitBreaker) *GuardedReleaseTimeSource {
+	return &GuardedReleaseTimeSource{
+		source:         source,
+		circuitBreaker: circuitbreaker.New(name, cfg),
+	}
+}
+
+// GetReleaseTime returns the release time of the given page path from the source, if its circuit is not open
+func (s *GuardedReleaseTimeSource) GetReleaseTime(ctx context.Context, path string) (time.Time, int, error) {
+	if !s.circuitBreaker.Allow(ctx) {
+		return time.Time{}, 0, ErrReleaseTimeSourceUnavailable
+	}
+
+	startedAt := time.Now()
+	releaseTime, statusCode, err := s.source.GetReleaseTime(ctx, path)
+	s.circuitBreaker.Record(ctx, isSourceFailure(statusCode, err), time.Since(startedAt))
+
+	return releaseTime, statusCode, err
+}
+
+// CircuitBreaker returns the circuit breaker of the source
+func (s *GuardedReleaseTimeSource) CircuitBreaker() *circuitbreaker.CircuitBreaker {
+	return s.circuitBreaker
+}
+
+// ReleaseTimeSourceCircuitBreakers returns the circuit breakers of the guarded sources in the given source or chain
+func ReleaseTimeSourceCircuitBreakers(source ReleaseTimeSource) []*circuitbreaker.CircuitBreaker {
+	switch s := source.(type) {
+	case *GuardedReleaseTimeSource:
+		return []*circuitbreaker.CircuitBreaker{s.circuitBreaker}
+	case ChainedReleaseTimeSource:
+		var circuitBreakers []*circuitbreaker.CircuitBreaker
+		for _, chainedSource := range s {
+			circuitBreakers = append(circuitBreakers, ReleaseTimeSourceCircuitBreakers(chainedSource)...)
+		}
+		return circuitBreakers
+	default:
+		return nil
+	}
+}
+
+// isSourceFailure determines if a call to the source failed. A release time that was not found is not a failure.
+func isSourceFailure(statusCode int, err error) bool {
+	return err != nil || (statusCode != http.StatusOK && statusCode != http.StatusNotFound)
+}
@@ -0,0 +1,234 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/circuitbreaker"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type stubReleaseTimeSource struct {
+	releaseTime time.Time
+	statusCode  int
+	err         error
+	calls       int
+}
+
+func (s *stubReleaseTimeSource) GetReleaseTime(_ context.Context, _ string) (time.Time, int, error) {
+	s.calls++
+	return s.releaseTime, s.statusCode, s.err
+}
+
+func TestFileReleaseTimeSource(t *testing.T) {
+	ctx := context.Background()
+	expectedReleaseTime := time.Date(2024, time.January, 31, 9, 30, 0, 0, time.UTC)
+
+	fileContents := map[string]string{
+		"releases.yaml": `
+release_times:
+  /economy/bulletins/gdp: 2024-01-31T09:30:00Z
+  /economy/bulletins/no-release: null
+`,
+		"releases.json": `{"release_times": {"/economy/bulletins/gdp": "2024-01-31T09:30:00Z", "/economy/bulletins/no-release": null}}`,
+	}
+
+	for fileName, contents := range fileContents {
+		Convey("Given a release time file in "+filepath.Ext(fileName)+" format", t, func() {
+			releaseTimeFile := filepath.Join(t.TempDir(), fileName)
+			So(os.WriteFile(releaseTimeFile, []byte(contents), 0o600), ShouldBeNil)
+			source, err := NewFileReleaseTimeSource(releaseTimeFile)
+			So(err, ShouldBeNil)
+
+			Convey("When the release time of a page in the file is requested", func() {
+				releaseTime, statusCode, err := source.GetReleaseTime(ctx, "/economy/bulletins/gdp")
+
+				Convey("Then its release time is returned", func() {
+					So(err, ShouldBeNil)
+					So(statusCode, ShouldEqual, http.StatusOK)
+					So(releaseTime.Equal(expectedReleaseTime), ShouldBeTrue)
+				})
+			})
+
+			Convey("When the release time of a page with a null release time is requested", func() {
+				releaseTime, statusCode, err := source.GetReleaseTime(ctx, "/economy/bulletins/no-release")
+
+				Convey("Then an empty release time is returned", func() {
+					So(err, ShouldBeNil)
+					So(statusCode, ShouldEqual, http.StatusOK)
+					So(releaseTime.IsZero(), ShouldBeTrue)
+				})
+			})
+
+			Convey("When the release time of a page that is not in the file is requested", func() {
+				_, statusCode, err := source.GetReleaseTime(ctx, "/economy/bulletins/unknown")
+
+				Convey("Then the page is not found", func() {
+					So(err, ShouldBeNil)
+					So(statusCode, ShouldEqual, http.StatusNotFound)
+				})
+			})
+		})
+	}
+
+	Convey("Given a release time file that is not valid", t, func() {
+		releaseTimeFile := filepath.Join(t.TempDir(), "releases.yaml")
+		So(os.WriteFile(releaseTimeFile, []byte(`release_times: [`), 0o600), ShouldBeNil)
+
+		Convey("When the source is created", func() {
+			_, err := NewFileReleaseTimeSource(releaseTimeFile)
+
+			Convey("Then an error is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "unable to parse the release time file")
+			})
+		})
+	})
+}
+
+func TestChainedReleaseTimeSource(t *testing.T) {
+	ctx := context.Background()
+	releaseTime := time.Date(2024, time.January, 31, 9, 30, 0, 0, time.UTC)
+	errSource := errors.New("source error")
+
+	Convey("Given a chain of release time sources", t, func() {
+		Convey("When the first source knows the page", func() {
+			first := &stubReleaseTimeSource{releaseTime: releaseTime, statusCode: http.StatusOK}
+			second := &stubReleaseTimeSource{statusCode: http.StatusOK}
+			result, statusCode, err := ChainedReleaseTimeSource{first, second}.GetReleaseTime(ctx, "/some-path")
+
+			Convey("Then its release time is returned without trying the next source", func() {
+				So(err, ShouldBeNil)
+				So(statusCode, ShouldEqual, http.StatusOK)
+				So(result, ShouldEqual, releaseTime)
+				So(second.calls, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the first source does not know the page or fails", func() {
+			for _, first := range []*stubReleaseTimeSource{{statusCode: http.StatusNotFound}, {err: errSource}} {
+				second := &stubReleaseTimeSource{releaseTime: releaseTime, statusCode: http.StatusOK}
+				result, statusCode, err := ChainedReleaseTimeSource{first, second}.GetReleaseTime(ctx, "/some-path")
+
+				So(err, ShouldBeNil)
+				So(statusCode, ShouldEqual, http.StatusOK)
+				So(result, ShouldEqual, releaseTime)
+			}
+		})
+
+		Convey("When no source knows the page", func() {
+			first := &stubReleaseTimeSource{statusCode: http.StatusNotFound}
+			second := &stubReleaseTimeSource{statusCode: http.StatusNotFound}
+			_, statusCode, err := ChainedReleaseTimeSource{first, second}.GetReleaseTime(ctx, "/some-path")
+
+			Convey("Then the page is not found", func() {
+				So(err, ShouldBeNil)
+				So(statusCode, ShouldEqual, http.StatusNotFound)
+			})
+		})
+
+		Convey("When a source fails and no other source knows the page", func() {
+			first := &stubReleaseTimeSource{err: errSource}
+			second := &stubReleaseTimeSource{statusCode: http.StatusNotFound}
+			_, _, err := ChainedReleaseTimeSource{first, second}.GetReleaseTime(ctx, "/some-path")
+
+			Convey("Then the error is returned", func() {
+				So(err, ShouldEqual, errSource)
+			})
+		})
+	})
+}
+
+func TestNewReleaseTimeSource(t *testing.T) {
+	Convey("Given the release time sources in the configuration", t, func() {
+		releaseTimeFile := filepath.Join(t.TempDir(), "releases.yaml")
+		So(os.WriteFile(releaseTimeFile, []byte(`release_times: {}`), 0o600), ShouldBeNil)
+		cfg := &config.Config{ReleaseTimeFile: releaseTimeFile}
+
+		Convey("When only the Legacy Cache API is configured", func() {
+			cfg.ReleaseTimeSources = []string{ReleaseTimeSourceLegacyCacheAPI}
+			source, err := NewReleaseTimeSource(cfg)
+
+			Convey("Then the Legacy Cache API source is returned, guarded by a circuit breaker", func() {
+				So(err, ShouldBeNil)
+				So(source, ShouldHaveSameTypeAs, &GuardedReleaseTimeSource{})
+				So(source.(*GuardedReleaseTimeSource).source, ShouldHaveSameTypeAs, &LegacyCacheAPISource{})
+			})
+		})
+
+		Convey("When the file and the Legacy Cache API are configured", func() {
+			cfg.ReleaseTimeSources = []string{ReleaseTimeSourceFile, ReleaseTimeSourceLegacyCacheAPI}
+			source, err := NewReleaseTimeSource(cfg)
+
+			Convey("Then a chain of both sources is returned, in order, with only the Legacy Cache API guarded", func() {
+				So(err, ShouldBeNil)
+				So(source, ShouldHaveSameTypeAs, ChainedReleaseTimeSource{})
+				So(source.(ChainedReleaseTimeSource)[0], ShouldHaveSameTypeAs, &FileReleaseTimeSource{})
+				So(source.(ChainedReleaseTimeSource)[1], ShouldHaveSameTypeAs, &GuardedReleaseTimeSource{})
+				So(ReleaseTimeSourceCircuitBreakers(source), ShouldHaveLength, 1)
+				So(ReleaseTimeSourceCircuitBreakers(source)[0].Name(), ShouldEqual, ReleaseTimeSourceLegacyCacheAPI)
+			})
+		})
+
+		Convey("When an unknown source is configured", func() {
+			cfg.ReleaseTimeSources = []string{"database"}
+			_, err := NewReleaseTimeSource(cfg)
+
+			Convey("Then an error is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When no source is configured", func() {
+			cfg.ReleaseTimeSources = nil
+			_, err := NewReleaseTimeSource(cfg)
+
+			Convey("Then an error is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestGuardedReleaseTimeSource(t *testing.T) {
+	Convey("Given a source guarded by a circuit breaker that opens after a single failure", t, func() {
+		ctx := context.Background()
+		stub := &stubReleaseTimeSource{err: errors.New("source error")}
+		source := NewGuardedReleaseTimeSource("some-source", stub, config.CircuitBreaker{
+			Enabled:      true,
+			FailureRatio: 0.5,
+			MinRequests:  1,
+			Window:       time.Minute,
+			OpenDuration: time.Hour,
+		})
+
+		Convey("When the source fails", func() {
+			_, _, err := source.GetReleaseTime(ctx, "/some-path")
+			So(err, ShouldNotBeNil)
+
+			Convey("Then the source is unavailable without being called again", func() {
+				_, _, err := source.GetReleaseTime(ctx, "/some-path")
+				So(err, ShouldEqual, ErrReleaseTimeSourceUnavailable)
+				So(stub.calls, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the source does not know the page", func() {
+			stub.err = nil
+			stub.statusCode = http.StatusNotFound
+			_, statusCode, err := source.GetReleaseTime(ctx, "/some-path")
+
+			Convey("Then the circuit stays closed", func() {
+				So(err, ShouldBeNil)
+				So(statusCode, ShouldEqual, http.StatusNotFound)
+				So(source.CircuitBreaker().State(), ShouldEqual, circuitbreaker.Closed)
+			})
+		})
+	})
+}
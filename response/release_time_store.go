@@ -0,0 +1,106 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+)
+
+// ReleaseTimeStore is a ReleaseTimeSource holding the release times sent to the proxy directly by the publishing tooling
+// (e.g. when a release is rescheduled in an emergency). It is checked before any other source, so its release times are
+// used straight away. Every release time is kept until the TTL has passed since it was stored or since the release
+// time itself, whichever is later, by when the other sources are expected to have caught up, or until the page is
+// published, which updates the other sources.
+type ReleaseTimeStore struct {
+	mutex   sync.RWMutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]releaseTimeStoreEntry
+}
+
+type releaseTimeStoreEntry struct {
+	releaseTime time.Time
+	expiresAt   time.Time
+}
+
+// ReleaseTimeEntry is the release time of a single page, as sent by the publishing tooling. A page with a nil release
+// time is known, but has no release time.
+type ReleaseTimeEntry struct {
+	Path        string     `json:"path"`
+	ReleaseTime *time.Time `json:"release_time"`
+}
+
+// NewReleaseTimeStore creates an empty ReleaseTimeStore
+func NewReleaseTimeStore(cfg *config.Config) *ReleaseTimeStore {
+	return &ReleaseTimeStore{
+		ttl:     cfg.ReleaseTimeStoreTTL,
+		now:     time.Now,
+		entries: make(map[string]releaseTimeStoreEntry),
+	}
+}
+
+// GetReleaseTime returns the release time of the given page path if it is in the store, otherwise the page is not found
+func (s *ReleaseTimeStore) GetReleaseTime(_ context.Context, path string) (time.Time, int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entry, ok := s.entries[path]
+	if !ok || !s.now().Before(entry.expiresAt) {
+		return time.Time{}, http.StatusNotFound, nil
+	}
+
+	return entry.releaseTime, http.StatusOK, nil
+}
+
+// Upsert stores the given release times, replacing any that are already stored for the same pages, and returns the
+// number of pages that were added and updated. Expired release times are removed at the same time.
+func (s *ReleaseTimeStore) Upsert(entries []ReleaseTimeEntry) (added, updated int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	for path, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, path)
+		}
+	}
+
+	for _, entry := range entries {
+		var releaseTime time.Time
+		if entry.ReleaseTime != nil {
+			releaseTime = *entry.ReleaseTime
+		}
+
+		expiresAt := now.Add(s.ttl)
+		if releaseTime.After(now) {
+			expiresAt = releaseTime.Add(s.ttl)
+		}
+
+		if _, ok := s.entries[entry.Path]; ok {
+			updated++
+		} else {
+			added++
+		}
+
+		s.entries[entry.Path] = releaseTimeStoreEntry{releaseTime: releaseTime, expiresAt: expiresAt}
+	}
+
+	return added, updated
+}
+
+// Evict removes the release time of the page at the given URI, once the page has been published
+func (s *ReleaseTimeStore) Evict(ctx context.Context, uri string) error {
+	pagePath, err := getPagePath(ctx, uri)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	delete(s.entries, pagePath)
+	s.mutex.Unlock()
+
+	return nil
+}
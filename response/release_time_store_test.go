@@ -0,0 +1,100 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReleaseTimeStore(t *testing.T) {
+	Convey("Given an empty release time store", t, func() {
+		ctx := context.Background()
+		now := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+		store := NewReleaseTimeStore(&config.Config{ReleaseTimeStoreTTL: time.Hour})
+		store.now = func() time.Time { return now }
+
+		upcomingRelease, pastRelease := now.Add(2*time.Hour), now.Add(-time.Hour)
+
+		Convey("When release times are stored", func() {
+			added, updated := store.Upsert([]ReleaseTimeEntry{
+				{Path: "/upcoming", ReleaseTime: &upcomingRelease},
+				{Path: "/past", ReleaseTime: &pastRelease},
+				{Path: "/without-release-time"},
+			})
+
+			Convey("Then they are all added", func() {
+				So(added, ShouldEqual, 3)
+				So(updated, ShouldEqual, 0)
+			})
+
+			Convey("Then their release times are returned", func() {
+				releaseTime, statusCode, err := store.GetReleaseTime(ctx, "/upcoming")
+				So(err, ShouldBeNil)
+				So(statusCode, ShouldEqual, http.StatusOK)
+				So(releaseTime, ShouldEqual, upcomingRelease)
+
+				releaseTime, statusCode, err = store.GetReleaseTime(ctx, "/without-release-time")
+				So(err, ShouldBeNil)
+				So(statusCode, ShouldEqual, http.StatusOK)
+				So(releaseTime.IsZero(), ShouldBeTrue)
+			})
+
+			Convey("Then any other page is not found", func() {
+				_, statusCode, err := store.GetReleaseTime(ctx, "/unknown")
+				So(err, ShouldBeNil)
+				So(statusCode, ShouldEqual, http.StatusNotFound)
+			})
+
+			Convey("And one of them is rescheduled", func() {
+				rescheduledRelease := upcomingRelease.Add(24 * time.Hour)
+				added, updated := store.Upsert([]ReleaseTimeEntry{
+					{Path: "/upcoming", ReleaseTime: &rescheduledRelease},
+					{Path: "/new", ReleaseTime: &pastRelease},
+				})
+
+				Convey("Then it is updated and its new release time is returned", func() {
+					So(added, ShouldEqual, 1)
+					So(updated, ShouldEqual, 1)
+					releaseTime, _, _ := store.GetReleaseTime(ctx, "/upcoming")
+					So(releaseTime, ShouldEqual, rescheduledRelease)
+				})
+			})
+
+			Convey("And one of the pages is published", func() {
+				So(store.Evict(ctx, "/upcoming"), ShouldBeNil)
+
+				Convey("Then its release time is removed and the others are kept", func() {
+					_, statusCode, _ := store.GetReleaseTime(ctx, "/upcoming")
+					So(statusCode, ShouldEqual, http.StatusNotFound)
+					_, statusCode, _ = store.GetReleaseTime(ctx, "/past")
+					So(statusCode, ShouldEqual, http.StatusOK)
+				})
+			})
+
+			Convey("And the TTL has passed", func() {
+				now = now.Add(time.Hour)
+
+				Convey("Then only the release times that are still to come are kept, until the TTL after them", func() {
+					_, statusCode, _ := store.GetReleaseTime(ctx, "/past")
+					So(statusCode, ShouldEqual, http.StatusNotFound)
+					_, statusCode, _ = store.GetReleaseTime(ctx, "/upcoming")
+					So(statusCode, ShouldEqual, http.StatusOK)
+
+					now = upcomingRelease.Add(time.Hour)
+					_, statusCode, _ = store.GetReleaseTime(ctx, "/upcoming")
+					So(statusCode, ShouldEqual, http.StatusNotFound)
+				})
+
+				Convey("Then an expired page that is stored again is added", func() {
+					added, updated := store.Upsert([]ReleaseTimeEntry{{Path: "/past", ReleaseTime: &pastRelease}})
+					So(added, ShouldEqual, 1)
+					So(updated, ShouldEqual, 0)
+				})
+			})
+		})
+	})
+}
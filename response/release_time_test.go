@@ -1,17 +1,22 @@
 package response
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 func TestGetReleaseTime(t *testing.T) {
 	Convey("Given a Legacy Cache API", t, func() {
+		ctx := context.Background()
+		client := newLegacyCacheAPIClient(&config.Config{LegacyCacheAPITimeout: time.Second})
 		var writeMockResponse func(w http.ResponseWriter) error
 		mockLegacyCacheAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 			err := writeMockResponse(w)
@@ -31,7 +36,7 @@ func TestGetReleaseTime(t *testing.T) {
 			}`
 			writeMockResponse = setMockResponse(cacheTimeResource, http.StatusOK)
 
-			releaseTime, statusCode, err := getReleaseTime("/some-valid-path", mockLegacyCacheAPI.URL)
+			releaseTime, statusCode, err := getReleaseTime(ctx, client, "/some-valid-path", mockLegacyCacheAPI.URL)
 
 			Convey("Then the result is the Release Time and status code with no errors", func() {
 				expectedReleaseTime, _ := time.Parse(time.RFC3339, "2024-01-31T01:23:45.678Z")
@@ -49,7 +54,7 @@ func TestGetReleaseTime(t *testing.T) {
 			}`
 			writeMockResponse = setMockResponse(cacheTimeResource, http.StatusOK)
 
-			releaseTime, statusCode, err := getReleaseTime("/some-valid-path", mockLegacyCacheAPI.URL)
+			releaseTime, statusCode, err := getReleaseTime(ctx, client, "/some-valid-path", mockLegacyCacheAPI.URL)
 
 			Convey("Then the result is an empty Release Time and status code with no errors", func() {
 				So(releaseTime.IsZero(), ShouldBeTrue)
@@ -61,7 +66,7 @@ func TestGetReleaseTime(t *testing.T) {
 		Convey("When 'getReleaseTime' is called and a Cache Time resource is not found in the API", func() {
 			writeMockResponse = setMockResponse("", http.StatusNotFound)
 
-			releaseTime, statusCode, err := getReleaseTime("/some-valid-path", mockLegacyCacheAPI.URL)
+			releaseTime, statusCode, err := getReleaseTime(ctx, client, "/some-valid-path", mockLegacyCacheAPI.URL)
 
 			Convey("Then the result is an empty Release Time and a Not Found status code with no errors", func() {
 				So(releaseTime.IsZero(), ShouldBeTrue)
@@ -73,7 +78,7 @@ func TestGetReleaseTime(t *testing.T) {
 		Convey("When 'getReleaseTime' is called and an unexpected status code is returned from the API", func() {
 			writeMockResponse = setMockResponse("", http.StatusBadGateway)
 
-			releaseTime, statusCode, err := getReleaseTime("/some-valid-path", mockLegacyCacheAPI.URL)
+			releaseTime, statusCode, err := getReleaseTime(ctx, client, "/some-valid-path", mockLegacyCacheAPI.URL)
 
 			Convey("Then the result is an empty Release Time and the same status code with no errors", func() {
 				So(releaseTime.IsZero(), ShouldBeTrue)
@@ -82,10 +87,25 @@ func TestGetReleaseTime(t *testing.T) {
 			})
 		})
 
+		Convey("When 'getReleaseTime' is called and the API takes longer than the client's timeout", func() {
+			writeMockResponse = func(w http.ResponseWriter) error {
+				time.Sleep(200 * time.Millisecond)
+				return setMockResponse("", http.StatusNotFound)(w)
+			}
+			slowClient := newLegacyCacheAPIClient(&config.Config{LegacyCacheAPITimeout: 50 * time.Millisecond})
+
+			_, _, err := getReleaseTime(ctx, slowClient, "/some-valid-path", mockLegacyCacheAPI.URL)
+
+			Convey("Then a timeout error should be returned", func() {
+				So(err, ShouldNotBeNil)
+				So(os.IsTimeout(err), ShouldBeTrue)
+			})
+		})
+
 		Convey("When 'getReleaseTime' is called and there is an error with the API", func() {
 			writeMockResponse = nil
 
-			_, _, err := getReleaseTime("/some-valid-path", "invalid-API-URL")
+			_, _, err := getReleaseTime(ctx, client, "/some-valid-path", "invalid-API-URL")
 
 			Convey("Then an error should be returned", func() {
 				So(err, ShouldNotBeNil)
@@ -0,0 +1,61 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+)
+
+// The headers that can carry the surrogate keys of a response, which the CDN strips before the response leaves the edge
+const (
+	SurrogateKeyHeader = "Surrogate-Key"
+	CacheTagHeader     = "Cache-Tag"
+)
+
+// surrogateKeyEscaper escapes the characters that separate surrogate keys in either header
+var surrogateKeyEscaper = strings.NewReplacer(" ", "%20", "\t", "%09", ",", "%2C")
+
+// surrogateKeys returns the value of the surrogate key header for the page at the given path, which has the page's
+// path and the ID of its Cache Time resource, so that every response belonging to the page can be purged from the CDN
+// at once. Surrogate-Key values are separated by spaces and Cache-Tag values by commas.
+func surrogateKeys(pagePath string, cfg *config.Config) string {
+	keys := []string{surrogateKeyEscaper.Replace(pagePath), cacheTimeID(pagePath)}
+
+	if strings.EqualFold(cfg.SurrogateKeyHeader, CacheTagHeader) {
+		return strings.Join(keys, ",")
+	}
+
+	return strings.Join(keys, " ")
+}
+
+// withSurrogateKeys adds the surrogate key header to the given headers, if it is configured and the page is known
+func withSurrogateKeys(overrideHeaders map[string]string, maxAge calculatedMaxAge, cfg *config.Config) map[string]string {
+	if cfg.SurrogateKeyHeader != "" && maxAge.pagePath != "" {
+		overrideHeaders[cfg.SurrogateKeyHeader] = surrogateKeys(maxAge.pagePath, cfg)
+	}
+
+	return overrideHeaders
+}
+
+// unmodifiedResponseSurrogateKeys returns the surrogate key header of a response that is passed through unmodified, if
+// it is configured and the CDN may store the response (i.e. it answers a GET or HEAD request and is neither no-store
+// nor private), so that it can be purged along with the rest of its page
+func unmodifiedResponseSurrogateKeys(ctx context.Context, req *http.Request, cacheControl *CacheControl, cfg *config.Config) map[string]string {
+	headers := map[string]string{}
+
+	if cfg.SurrogateKeyHeader == "" || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return headers
+	}
+
+	if cacheControl.Has(noStoreDirective) || cacheControl.Has(privateString) {
+		return headers
+	}
+
+	if pagePath, err := getPagePath(ctx, req.RequestURI); err == nil {
+		headers[cfg.SurrogateKeyHeader] = surrogateKeys(pagePath, cfg)
+	}
+
+	return headers
+}
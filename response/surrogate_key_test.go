@@ -0,0 +1,117 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSurrogateKeys(t *testing.T) {
+	Convey("Given the path of a page", t, func() {
+		pagePath := "/economy"
+
+		Convey("When the surrogate key header is Surrogate-Key", func() {
+			keys := surrogateKeys(pagePath, &config.Config{SurrogateKeyHeader: SurrogateKeyHeader})
+
+			Convey("Then the page path and its cache time ID should be separated by a space", func() {
+				So(keys, ShouldEqual, "/economy 6a7c21326d723b5e3da8e8c06ebab3dd")
+			})
+		})
+
+		Convey("When the surrogate key header is Cache-Tag", func() {
+			keys := surrogateKeys(pagePath, &config.Config{SurrogateKeyHeader: "cache-tag"})
+
+			Convey("Then the page path and its cache time ID should be separated by a comma", func() {
+				So(keys, ShouldEqual, "/economy,6a7c21326d723b5e3da8e8c06ebab3dd")
+			})
+		})
+	})
+
+	Convey("Given the path of a page that has a space and a comma", t, func() {
+		keys := surrogateKeys("/a b,c", &config.Config{SurrogateKeyHeader: SurrogateKeyHeader})
+
+		Convey("Then they should be escaped, so that the page path is a single key", func() {
+			So(keys, ShouldStartWith, "/a%20b%2Cc ")
+		})
+	})
+}
+
+func TestWithSurrogateKeys(t *testing.T) {
+	Convey("Given a calculated max-age for a page", t, func() {
+		maxAge := calculatedMaxAge{seconds: 900, pagePath: "/economy"}
+
+		Convey("When no surrogate key header is configured", func() {
+			headers := withSurrogateKeys(map[string]string{}, maxAge, &config.Config{})
+
+			Convey("Then no header should be added", func() {
+				So(headers, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a surrogate key header is configured", func() {
+			headers := withSurrogateKeys(map[string]string{}, maxAge, &config.Config{SurrogateKeyHeader: CacheTagHeader})
+
+			Convey("Then the header should be added", func() {
+				So(headers, ShouldContainKey, CacheTagHeader)
+			})
+		})
+	})
+
+	Convey("Given a calculated max-age without a page path", t, func() {
+		headers := withSurrogateKeys(map[string]string{}, calculatedMaxAge{seconds: 900}, &config.Config{SurrogateKeyHeader: CacheTagHeader})
+
+		Convey("Then no header should be added", func() {
+			So(headers, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestUnmodifiedResponseSurrogateKeys(t *testing.T) {
+	Convey("Given a surrogate key header is configured", t, func() {
+		ctx := context.Background()
+		cfg := &config.Config{SurrogateKeyHeader: SurrogateKeyHeader}
+
+		Convey("When a GET response can be stored by the CDN", func() {
+			req := httptest.NewRequest(http.MethodGet, "/economy", http.NoBody)
+			headers := unmodifiedResponseSurrogateKeys(ctx, req, ParseCacheControl("public, max-age=60"), cfg)
+
+			Convey("Then the header should be added", func() {
+				So(headers, ShouldResemble, map[string]string{SurrogateKeyHeader: "/economy 6a7c21326d723b5e3da8e8c06ebab3dd"})
+			})
+		})
+
+		Convey("When a response cannot be stored by the CDN", func() {
+			req := httptest.NewRequest(http.MethodGet, "/economy", http.NoBody)
+
+			for _, cacheControl := range []string{"no-store", "private, max-age=60"} {
+				headers := unmodifiedResponseSurrogateKeys(ctx, req, ParseCacheControl(cacheControl), cfg)
+
+				Convey("Then no header should be added for "+cacheControl, func() {
+					So(headers, ShouldBeEmpty)
+				})
+			}
+		})
+
+		Convey("When the response answers a POST request", func() {
+			req := httptest.NewRequest(http.MethodPost, "/economy", http.NoBody)
+			headers := unmodifiedResponseSurrogateKeys(ctx, req, ParseCacheControl("public, max-age=60"), cfg)
+
+			Convey("Then no header should be added", func() {
+				So(headers, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given no surrogate key header is configured", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/economy", http.NoBody)
+		headers := unmodifiedResponseSurrogateKeys(context.Background(), req, ParseCacheControl("public, max-age=60"), &config.Config{})
+
+		Convey("Then no header should be added", func() {
+			So(headers, ShouldBeEmpty)
+		})
+	})
+}
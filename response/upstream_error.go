@@ -0,0 +1,33 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// UpstreamErrorHeader is the response header naming the upstream service that failed to respond
+const UpstreamErrorHeader = "X-Upstream-Error"
+
+// WriteUpstreamError writes the response for a request that could not be completed because the upstream service
+// failed. The response can be cached for the errored cache time, as of the clock's time for the request, so that a
+// failing upstream is not overwhelmed.
+func WriteUpstreamError(ctx context.Context, w http.ResponseWriter, req *http.Request, upstream string, statusCode int, cfg *config.Config, clock *Clock) {
+	erroredCacheTime := int(cfg.CacheTimeErrored.Seconds())
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	for name, value := range maxAgeHeaders(ParseCacheControl(publicString), erroredCacheTime, time.Time{}, -1, -1, clock.Now(ctx, req), cfg) {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set(UpstreamErrorHeader, upstream)
+	w.WriteHeader(statusCode)
+
+	if _, err := fmt.Fprintf(w, "%s: the upstream service failed to respond\n", http.StatusText(statusCode)); err != nil {
+		log.Error(ctx, "error writing the upstream error response", err)
+	}
+}
@@ -2,10 +2,9 @@ package response
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"net/http"
-	"slices"
+	"time"
 
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
 	"github.com/ONSdigital/log.go/v2/log"
@@ -15,25 +14,61 @@ const (
 	publicString       = "public"
 	privateString      = "private"
 	cacheControlHeader = "Cache-Control"
+	expiresHeader      = "Expires"
+	dateHeader         = "Date"
+	ageHeader          = "Age"
+
+	maxAgeDirective               = "max-age"
+	sMaxAgeDirective              = "s-maxage"
+	staleWhileRevalidateDirective = "stale-while-revalidate"
+	staleIfErrorDirective         = "stale-if-error"
+	noStoreDirective              = "no-store"
 )
 
-func WriteResponse(ctx context.Context, w http.ResponseWriter, serviceResponse *http.Response, req *http.Request, cfg *config.Config) {
-	if !isGetOrHead(req.Method) {
-		writeUnmodifiedResponse(ctx, w, serviceResponse)
-	} else if !isCacheableStatusCode(serviceResponse.StatusCode) {
-		writeUnmodifiedResponse(ctx, w, serviceResponse)
-	} else if cacheControl := serviceResponse.Header.Get(cacheControlHeader); !shouldCalculateMaxAge(cacheControl) {
-		writeUnmodifiedResponse(ctx, w, serviceResponse)
+func WriteResponse(ctx context.Context, w http.ResponseWriter, serviceResponse *http.Response, req *http.Request, upstream string, cfg *config.Config, policy *CachePolicy, releaseTimes ReleaseTimeSource, clock *Clock) {
+	rule := policy.Match(req, upstream, serviceResponse.StatusCode)
+	cacheControl := ParseCacheControl(serviceResponse.Header.Values(cacheControlHeader)...)
+
+	if rule == nil || rule.Action == CachePolicyPassThrough || upstreamCacheControlWins(cacheControl, cfg) {
+		writeUnmodifiedResponse(ctx, w, serviceResponse, unmodifiedResponseSurrogateKeys(ctx, req, cacheControl, cfg))
+	} else if rule.Action == CachePolicyFixed {
+		maxAgeInSeconds := int(ruleCacheTime(rule, req, cfg).Seconds())
+		log.Info(ctx, "writing response max-age", log.Data{"maxAge": maxAgeInSeconds, "rule": rule.Name})
+		fixedMaxAge := calculatedMaxAge{seconds: maxAgeInSeconds}
+		if cfg.SurrogateKeyHeader != "" {
+			// The page path is only needed for the surrogate keys, as a fixed max-age ignores the release time
+			if pagePath, err := getPagePath(ctx, req.RequestURI); err == nil {
+				fixedMaxAge.pagePath = pagePath
+			}
+		}
+		writeResponseWithMaxAge(ctx, w, serviceResponse, cacheControl, fixedMaxAge, clock.Now(ctx, req), cfg)
 	} else {
-		maxAgeInSeconds, ageIsCalculated := maxAge(ctx, req.RequestURI, cfg)
-		log.Info(ctx, "writing response max-age", log.Data{"maxAge": maxAgeInSeconds, "ageIsCalculated": ageIsCalculated})
-		writeResponseWithMaxAge(ctx, w, serviceResponse, maxAgeInSeconds, ageIsCalculated, cfg)
+		now := clock.Now(ctx, req)
+		pageMaxAge := maxAge(ctx, req.RequestURI, ruleCacheTime(rule, req, cfg), now, cfg, releaseTimes)
+		log.Info(ctx, "writing response max-age", log.Data{"maxAge": pageMaxAge.seconds, "ageIsCalculated": !pageMaxAge.countdownTo.IsZero(), "rule": rule.Name})
+		writeResponseWithMaxAge(ctx, w, serviceResponse, cacheControl, pageMaxAge, now, cfg)
 	}
 }
 
-func writeResponse(ctx context.Context, w http.ResponseWriter, serviceResponse *http.Response, overrideHeaders map[string]string) {
-	// Copy the service response's headers
-	for name, values := range serviceResponse.Header {
+// ruleCacheTime returns the rule's cache time for the request. The default and long cache times are jittered, so that
+// they are applied before any countdown to a release time is calculated and are never longer than the countdown.
+func ruleCacheTime(rule *CachePolicyRule, req *http.Request, cfg *config.Config) time.Duration {
+	cacheTime := rule.maxAge(cfg)
+	if rule.isJittered() {
+		cacheTime = jitter(cacheTime, req.URL.Path, cfg.CacheTimeJitterPercent)
+	}
+
+	return cacheTime
+}
+
+func writeResponse(ctx context.Context, w http.ResponseWriter, serviceResponse *http.Response, overrideHeaders map[string]string, removedHeaders ...string) {
+	// Copy the service response's headers, except for the hop-by-hop ones and any that are removed
+	serviceHeaders := serviceResponse.Header.Clone()
+	RemoveHopByHopHeaders(serviceHeaders)
+	for _, name := range removedHeaders {
+		serviceHeaders.Del(name)
+	}
+	for name, values := range serviceHeaders {
 		for _, value := range values {
 			w.Header().Add(name, value)
 		}
@@ -57,47 +92,171 @@ func writeResponse(ctx context.Context, w http.ResponseWriter, serviceResponse *
 	}
 }
 
-func writeUnmodifiedResponse(ctx context.Context, w http.ResponseWriter, serviceResponse *http.Response) {
-	noAdditionalHeaders := map[string]string{}
-	writeResponse(ctx, w, serviceResponse, noAdditionalHeaders)
+// writeUnmodifiedResponse writes the upstream's response as it is, apart from any additional headers (e.g. the surrogate
+// keys), which do not change how the response is cached
+func writeUnmodifiedResponse(ctx context.Context, w http.ResponseWriter, serviceResponse *http.Response, additionalHeaders map[string]string) {
+	writeResponse(ctx, w, serviceResponse, additionalHeaders)
 }
 
-func writeResponseWithMaxAge(ctx context.Context, w http.ResponseWriter, serviceResponse *http.Response, maxAge int, ageIsCalculated bool, cfg *config.Config) {
-	overrideHeaders := make(map[string]string)
+// writeResponseWithMaxAge writes the response with the given max-age, as of now, merged into the upstream's Cache-Control
+// directives (or "public", if it has none), or with no-store if the page's release is within the release embargo
+// window. The upstream's Age header is removed, as the max-age is relative to now rather than to when the upstream
+// generated the response.
+func writeResponseWithMaxAge(ctx context.Context, w http.ResponseWriter, serviceResponse *http.Response, cacheControl *CacheControl, maxAge calculatedMaxAge, now time.Time, cfg *config.Config) {
+	if isInReleaseEmbargo(maxAge, now, cfg) {
+		log.Info(ctx, "issuing release embargo", log.Data{"releaseTime": maxAge.releaseTime})
+		writeResponse(ctx, w, serviceResponse, withSurrogateKeys(embargoHeaders(cacheControl, now, cfg), maxAge, cfg), ageHeader)
+		return
+	}
 
-	cacheControl := publicString
-	// Get the original Cache-Control value and - if non-blank - use that instead of above
-	originalCacheControl := serviceResponse.Header.Get(cacheControlHeader)
-	if originalCacheControl != "" {
-		cacheControl = originalCacheControl
+	if cacheControl.Len() == 0 {
+		cacheControl.Set(publicString)
 	}
-	staleWhileRevalidateOption := ""
-	if cfg.StaleWhileRevalidateSeconds >= 0 {
-		staleWhileRevalidateOption = fmt.Sprintf(", stale-while-revalidate=%d", cfg.StaleWhileRevalidateSeconds)
+
+	staleIfError := staleIfErrorSeconds(maxAge, now, cfg)
+	if staleIfError < 0 && (!maxAge.releaseTime.IsZero() || maxAge.releaseTimeUnknown) {
+		// The upstream's stale-if-error must not outlive a release either
+		cacheControl.Del(staleIfErrorDirective)
 	}
+
+	overrideHeaders := maxAgeHeaders(cacheControl, maxAge.seconds, maxAge.countdownTo, cfg.StaleWhileRevalidateSeconds, staleIfError, now, cfg)
+	writeResponse(ctx, w, serviceResponse, withSurrogateKeys(overrideHeaders, maxAge, cfg), ageHeader)
+}
+
+// maxAgeHeaders returns the headers that give a response the given max-age. The computed directives replace any of the
+// same name in cacheControl, and the stale-while-revalidate and stale-if-error directives are only set if they are not
+// negative. By default, the CDN's max-age is the s-maxage directive of the Cache-Control header. If a CDN cache header
+// is configured, the CDN's max-age is set in that header instead and the max-age in the Cache-Control header, for
+// browsers, is at most the browser cache time. When counting down to a release, both max-ages count down (unless the
+// max-age countdown is disabled, in which case browsers get a max-age of 0). The Date and Expires headers are set to
+// match the browsers' max-age.
+func maxAgeHeaders(cacheControl *CacheControl, maxAge int, countdownTo time.Time, staleWhileRevalidate, staleIfError int64, now time.Time, cfg *config.Config) map[string]string {
+	overrideHeaders := make(map[string]string)
+
 	serverMaxAge := maxAge
-	if !cfg.EnableMaxAgeCountdown && ageIsCalculated {
+	if !cfg.EnableMaxAgeCountdown && !countdownTo.IsZero() {
 		maxAge = 0
+		countdownTo = time.Time{}
+	}
+
+	if cfg.CDNCacheHeader == "" {
+		cacheControl.SetSeconds(sMaxAgeDirective, int64(serverMaxAge))
+		cacheControl.SetSeconds(maxAgeDirective, int64(maxAge))
+		setStaleDirectives(cacheControl, staleWhileRevalidate, staleIfError)
+	} else {
+		if browserCacheTime := int(cfg.CacheTimeBrowser.Seconds()); maxAge > browserCacheTime {
+			maxAge = browserCacheTime
+			countdownTo = time.Time{}
+		}
+		cacheControl.Del(sMaxAgeDirective)
+		cacheControl.SetSeconds(maxAgeDirective, int64(maxAge))
+		setStaleDirectives(cacheControl, staleWhileRevalidate, staleIfError)
+
+		// A private response must not be stored by the CDN, which would ignore the Cache-Control header
+		if !cacheControl.Has(privateString) {
+			cdnCacheControl := &CacheControl{}
+			cdnCacheControl.SetSeconds(maxAgeDirective, int64(serverMaxAge))
+			for _, name := range []string{staleWhileRevalidateDirective, staleIfErrorDirective} {
+				if value, ok := cacheControl.Get(name); ok {
+					cdnCacheControl.SetValue(name, value)
+				}
+			}
+			overrideHeaders[cfg.CDNCacheHeader] = cdnCacheControl.String()
+		}
 	}
-	overrideHeaders[cacheControlHeader] = fmt.Sprintf("%s, s-maxage=%d, max-age=%d%s", cacheControl, serverMaxAge, maxAge, staleWhileRevalidateOption)
-	writeResponse(ctx, w, serviceResponse, overrideHeaders)
+	overrideHeaders[cacheControlHeader] = cacheControl.String()
+
+	overrideHeaders[dateHeader] = formatHTTPDate(now)
+	overrideHeaders[expiresHeader] = formatHTTPDate(expires(now, maxAge, countdownTo))
+
+	return overrideHeaders
 }
 
-func isGetOrHead(method string) bool {
-	return method == http.MethodGet || method == http.MethodHead
+// isInReleaseEmbargo determines if the page's release is within the release embargo window, in which case the response
+// must not be stored at all, in case a cache's clock is skewed and it serves the response after the release
+func isInReleaseEmbargo(maxAge calculatedMaxAge, now time.Time, cfg *config.Config) bool {
+	if cfg.ReleaseEmbargoWindow <= 0 || !maxAge.releaseTime.After(now) {
+		return false
+	}
+
+	return maxAge.releaseTime.Sub(now) <= cfg.ReleaseEmbargoWindow
 }
 
-func isCacheableStatusCode(statusCode int) bool {
-	cacheableStatusCodeExceptions := []int{301, 302, 304, 307, 308, 404}
+// embargoHeaders returns the headers that stop a response from being stored by the CDN or browsers. The upstream's
+// other directives are kept, but any that would let the response be stored or served stale are removed.
+func embargoHeaders(cacheControl *CacheControl, now time.Time, cfg *config.Config) map[string]string {
+	for _, name := range []string{publicString, sMaxAgeDirective, maxAgeDirective, staleWhileRevalidateDirective, staleIfErrorDirective} {
+		cacheControl.Del(name)
+	}
+	cacheControl.Set(noStoreDirective)
+
+	overrideHeaders := map[string]string{cacheControlHeader: cacheControl.String()}
+	if cfg.CDNCacheHeader != "" {
+		overrideHeaders[cfg.CDNCacheHeader] = noStoreDirective
+	}
+
+	overrideHeaders[dateHeader] = formatHTTPDate(now)
+	overrideHeaders[expiresHeader] = formatHTTPDate(now)
 
-	return statusCode < 300 || slices.Contains(cacheableStatusCodeExceptions, statusCode)
+	return overrideHeaders
 }
 
-func shouldCalculateMaxAge(cacheControlValue string) bool {
-	switch cacheControlValue {
-	case "", publicString, privateString:
-		return true
-	default:
-		return false
+func setStaleDirectives(cacheControl *CacheControl, staleWhileRevalidate, staleIfError int64) {
+	if staleWhileRevalidate >= 0 {
+		cacheControl.SetSeconds(staleWhileRevalidateDirective, staleWhileRevalidate)
+	}
+	if staleIfError >= 0 {
+		cacheControl.SetSeconds(staleIfErrorDirective, staleIfError)
+	}
+}
+
+// staleIfErrorSeconds returns the value of the stale-if-error directive, or -1 if it must not be set. A stale copy of a
+// page must never be served after the page's release, so the directive is not set when the release time is unknown,
+// during the countdown to a release or within the publish expiry offset after it, and it is limited to the time between
+// the max-age expiring and an upcoming release.
+func staleIfErrorSeconds(maxAge calculatedMaxAge, now time.Time, cfg *config.Config) int64 {
+	if maxAge.releaseTimeUnknown {
+		return -1
+	}
+
+	if cfg.StaleIfErrorSeconds < 0 || maxAge.releaseTime.IsZero() {
+		return cfg.StaleIfErrorSeconds
 	}
+
+	if !maxAge.countdownTo.IsZero() || !maxAge.releaseTime.After(now) {
+		return -1
+	}
+
+	secondsUntilRelease := int64(maxAge.releaseTime.Sub(now).Seconds()) - int64(maxAge.seconds)
+	if secondsUntilRelease <= 0 {
+		return -1
+	}
+
+	return min(cfg.StaleIfErrorSeconds, secondsUntilRelease)
+}
+
+// expires returns the time at which a response with the given max-age expires, which is exactly the release time when
+// the max-age counts down to it
+func expires(now time.Time, maxAge int, countdownTo time.Time) time.Time {
+	if !countdownTo.IsZero() {
+		return countdownTo
+	}
+
+	return now.Add(time.Duration(maxAge) * time.Second)
+}
+
+func formatHTTPDate(t time.Time) string {
+	return t.UTC().Format(http.TimeFormat)
+}
+
+// upstreamCacheControlWins determines if the upstream's Cache-Control header has any of the configured upstream cache
+// directives, in which case it takes precedence over the computed max-age and the response is left unmodified
+func upstreamCacheControlWins(cacheControl *CacheControl, cfg *config.Config) bool {
+	for _, name := range cfg.UpstreamCacheDirectives {
+		if cacheControl.Has(name) {
+			return true
+		}
+	}
+
+	return false
 }
@@ -1,40 +1,471 @@
 package response
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
 
-func TestShouldCalculateMaxAge(t *testing.T) {
-	Convey("Given a series of possible values for the Cache-Control header", t, func() {
+func TestUpstreamCacheControlWins(t *testing.T) {
+	Convey("Given a series of possible values for the Cache-Control header and the default upstream cache directives", t, func() {
+		cfg := &config.Config{UpstreamCacheDirectives: []string{"no-store", "no-cache", "max-age", "s-maxage"}}
 		testCases := []struct {
 			cacheControl string
 			expected     bool
 		}{
-			{cacheControl: "", expected: true},
-			{cacheControl: "private", expected: true},
-			{cacheControl: "public", expected: true},
-			{cacheControl: "max-age=604800", expected: false},
-			{cacheControl: "max-age=604800, must-revalidate", expected: false},
-			{cacheControl: "s-maxage=604800", expected: false},
-			{cacheControl: "no-cache", expected: false},
-			{cacheControl: "no-store", expected: false},
-			{cacheControl: "public, max-age=604800", expected: false},
-			{cacheControl: "public, max-age=604800, immutable", expected: false},
-			{cacheControl: "max-age=604800, stale-while-revalidate=86400", expected: false},
-			{cacheControl: "max-age=604800, stale-if-error=86400", expected: false},
-			{cacheControl: "must-understand, no-store", expected: false},
+			{cacheControl: "", expected: false},
+			{cacheControl: "private", expected: false},
+			{cacheControl: "public", expected: false},
+			{cacheControl: "Public", expected: false},
+			{cacheControl: "public, must-revalidate", expected: false},
 			{cacheControl: "no-transform", expected: false},
+			{cacheControl: "max-age=604800", expected: true},
+			{cacheControl: "MAX-AGE=604800", expected: true},
+			{cacheControl: "max-age=604800, must-revalidate", expected: true},
+			{cacheControl: "s-maxage=604800", expected: true},
+			{cacheControl: "no-cache", expected: true},
+			{cacheControl: `no-cache="Set-Cookie"`, expected: true},
+			{cacheControl: "no-store", expected: true},
+			{cacheControl: "public, max-age=604800", expected: true},
+			{cacheControl: "public, max-age=604800, immutable", expected: true},
+			{cacheControl: "max-age=604800, stale-while-revalidate=86400", expected: true},
+			{cacheControl: "max-age=604800, stale-if-error=86400", expected: true},
+			{cacheControl: "must-understand, no-store", expected: true},
+			{cacheControl: `private="no-store, max-age"`, expected: false},
 		}
-		Convey("When the 'shouldCalculateMaxAge' function is called", func() {
+		Convey("When the 'upstreamCacheControlWins' function is called", func() {
 			for _, tc := range testCases {
-				result := shouldCalculateMaxAge(tc.cacheControl)
+				result := upstreamCacheControlWins(ParseCacheControl(tc.cacheControl), cfg)
 				Convey(fmt.Sprintf(`Then the result should be "%t" when the Cache-Control header is %q`, tc.expected, tc.cacheControl), func() {
 					So(result, ShouldEqual, tc.expected)
 				})
 			}
 		})
 	})
+
+	Convey("Given only no-store as an upstream cache directive", t, func() {
+		cfg := &config.Config{UpstreamCacheDirectives: []string{"no-store"}}
+
+		Convey("Then an upstream max-age should not win over the computed one", func() {
+			So(upstreamCacheControlWins(ParseCacheControl("public, max-age=60"), cfg), ShouldBeFalse)
+		})
+
+		Convey("Then an upstream no-store should win", func() {
+			So(upstreamCacheControlWins(ParseCacheControl("No-Store"), cfg), ShouldBeTrue)
+		})
+	})
+}
+
+func TestWriteResponseWithMaxAgeMerge(t *testing.T) {
+	Convey("Given the stale-while-revalidate and stale-if-error times", t, func() {
+		ctx := context.Background()
+		cfg := &config.Config{StaleWhileRevalidateSeconds: 30, StaleIfErrorSeconds: 86400, EnableMaxAgeCountdown: true}
+
+		write := func(cacheControl string, maxAge calculatedMaxAge) string {
+			w := httptest.NewRecorder()
+			serviceResponse := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("body"))}
+			writeResponseWithMaxAge(ctx, w, serviceResponse, ParseCacheControl(cacheControl), maxAge, time.Now(), cfg)
+			return w.Header().Get("Cache-Control")
+		}
+
+		Convey("When the upstream has no Cache-Control header", func() {
+			Convey("Then the computed directives should be added to public", func() {
+				So(write("", calculatedMaxAge{seconds: 900}), ShouldEqual, "public, s-maxage=900, max-age=900, stale-while-revalidate=30, stale-if-error=86400")
+			})
+		})
+
+		Convey("When the upstream sets other directives", func() {
+			Convey("Then they should be kept, in lower case, before the computed directives", func() {
+				So(write("Public, Must-Revalidate", calculatedMaxAge{seconds: 900}), ShouldEqual, "public, must-revalidate, s-maxage=900, max-age=900, stale-while-revalidate=30, stale-if-error=86400")
+			})
+		})
+
+		Convey("When the upstream sets directives that the proxy computes", func() {
+			Convey("Then the computed values should replace them where they were", func() {
+				So(write("stale-while-revalidate=5, public", calculatedMaxAge{seconds: 900}), ShouldEqual, "stale-while-revalidate=30, public, s-maxage=900, max-age=900, stale-if-error=86400")
+			})
+		})
+
+		Convey("When the upstream sets a quoted value", func() {
+			Convey("Then it should stay quoted", func() {
+				So(write(`private="Set-Cookie, X-Token"`, calculatedMaxAge{seconds: 900}), ShouldEqual, `private="Set-Cookie, X-Token", s-maxage=900, max-age=900, stale-while-revalidate=30, stale-if-error=86400`)
+			})
+		})
+
+		Convey("When the upstream sets stale-if-error but the max-age counts down to a release", func() {
+			releaseTime := time.Now().Add(time.Minute)
+
+			Convey("Then the upstream's stale-if-error should be removed", func() {
+				So(write("public, stale-if-error=600", calculatedMaxAge{seconds: 60, countdownTo: releaseTime, releaseTime: releaseTime}), ShouldEqual, "public, s-maxage=60, max-age=60, stale-while-revalidate=30")
+			})
+		})
+	})
+}
+
+func TestWriteResponseWithUnknownReleaseTime(t *testing.T) {
+	Convey("Given a release time source that is unavailable and a stale-if-error time of a day", t, func() {
+		ctx := context.Background()
+		cfg := &config.Config{
+			CacheTimeDefault:             15 * time.Minute,
+			LegacyCacheAPIDegradedMaxAge: 30 * time.Second,
+			StaleWhileRevalidateSeconds:  -1,
+			StaleIfErrorSeconds:          86400,
+		}
+		policy, err := LoadCachePolicy(ctx, cfg)
+		So(err, ShouldBeNil)
+		clock, err := NewClock(cfg)
+		So(err, ShouldBeNil)
+		source := &stubReleaseTimeSource{err: ErrReleaseTimeSourceUnavailable}
+
+		write := func(cacheControl string) string {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/economy", http.NoBody)
+			serviceResponse := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("body"))}
+			if cacheControl != "" {
+				serviceResponse.Header.Set("Cache-Control", cacheControl)
+			}
+			WriteResponse(ctx, w, serviceResponse, req, "babbage", cfg, policy, source, clock)
+			return w.Header().Get("Cache-Control")
+		}
+
+		Convey("When a page is requested", func() {
+			Convey("Then it should have the degraded max-age without stale-if-error, as it may be about to be released", func() {
+				So(write(""), ShouldEqual, "public, s-maxage=30, max-age=30")
+			})
+		})
+
+		Convey("When the upstream sets stale-if-error", func() {
+			Convey("Then the upstream's stale-if-error should be removed", func() {
+				So(write("public, stale-if-error=600"), ShouldEqual, "public, s-maxage=30, max-age=30")
+			})
+		})
+	})
+}
+
+func TestRuleCacheTime(t *testing.T) {
+	Convey("Given a cache time jitter percentage of 10", t, func() {
+		cfg := &config.Config{CacheTimeDefault: 15 * time.Minute, CacheTimeLong: 4 * time.Hour, CacheTimeNotFound: 10 * time.Second, CacheTimeJitterPercent: 10}
+		req := httptest.NewRequest(http.MethodGet, "/economy?page=2", http.NoBody)
+
+		Convey("When the rule uses the default cache time", func() {
+			cacheTime := ruleCacheTime(&CachePolicyRule{Action: CachePolicyReleaseTime}, req, cfg)
+
+			Convey("Then it should be jittered by the request's path", func() {
+				So(cacheTime, ShouldEqual, jitter(15*time.Minute, "/economy", 10))
+				So(cacheTime, ShouldNotEqual, 15*time.Minute)
+				So(cacheTime, ShouldBeBetweenOrEqual, 810*time.Second, 990*time.Second)
+			})
+		})
+
+		Convey("When the rule uses the long cache time", func() {
+			cacheTime := ruleCacheTime(&CachePolicyRule{Action: CachePolicyFixed, MaxAge: CacheTimeLong}, req, cfg)
+
+			Convey("Then it should be jittered by the request's path", func() {
+				So(cacheTime, ShouldEqual, jitter(4*time.Hour, "/economy", 10))
+			})
+		})
+
+		Convey("When the rule uses another cache time", func() {
+			notFound := ruleCacheTime(&CachePolicyRule{Action: CachePolicyReleaseTime, MaxAge: CacheTimeNotFound}, req, cfg)
+			duration := ruleCacheTime(&CachePolicyRule{Action: CachePolicyFixed, MaxAge: "5m"}, req, cfg)
+
+			Convey("Then it should not be jittered", func() {
+				So(notFound, ShouldEqual, 10*time.Second)
+				So(duration, ShouldEqual, 5*time.Minute)
+			})
+		})
+	})
+}
+
+func TestWriteResponseWithMaxAgeExpiry(t *testing.T) {
+	Convey("Given an upstream response with Age and Date headers", t, func() {
+		ctx := context.Background()
+		cfg := &config.Config{StaleWhileRevalidateSeconds: -1, StaleIfErrorSeconds: -1, EnableMaxAgeCountdown: true}
+
+		newServiceResponse := func() *http.Response {
+			header := http.Header{}
+			header.Set("Age", "120")
+			header.Set("Date", "Mon, 01 Jan 2024 00:00:00 GMT")
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader("body"))}
+		}
+
+		Convey("When the response is written with a fixed max-age", func() {
+			w := httptest.NewRecorder()
+			writeResponseWithMaxAge(ctx, w, newServiceResponse(), &CacheControl{}, calculatedMaxAge{seconds: 900}, time.Now(), cfg)
+
+			Convey("Then the Expires header should be the max-age after the Date header", func() {
+				date, err := http.ParseTime(w.Header().Get("Date"))
+				So(err, ShouldBeNil)
+				So(date, ShouldHappenWithin, 2*time.Second, time.Now())
+				expiry, err := http.ParseTime(w.Header().Get("Expires"))
+				So(err, ShouldBeNil)
+				So(expiry.Sub(date), ShouldEqual, 900*time.Second)
+			})
+
+			Convey("Then the upstream's Age header should be removed", func() {
+				So(w.Header().Values("Age"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the response is written with a max-age counting down to a release", func() {
+			releaseTime := time.Now().Add(90 * time.Second).Truncate(time.Second)
+
+			Convey("And the max-age countdown is enabled", func() {
+				w := httptest.NewRecorder()
+				writeResponseWithMaxAge(ctx, w, newServiceResponse(), &CacheControl{}, calculatedMaxAge{seconds: 89, countdownTo: releaseTime, releaseTime: releaseTime}, time.Now(), cfg)
+
+				Convey("Then the Expires header should be the release time", func() {
+					So(w.Header().Get("Cache-Control"), ShouldEqual, "public, s-maxage=89, max-age=89")
+					So(w.Header().Get("Expires"), ShouldEqual, releaseTime.UTC().Format(http.TimeFormat))
+				})
+			})
+
+			Convey("And the max-age countdown is disabled", func() {
+				cfg.EnableMaxAgeCountdown = false
+				w := httptest.NewRecorder()
+				writeResponseWithMaxAge(ctx, w, newServiceResponse(), &CacheControl{}, calculatedMaxAge{seconds: 89, countdownTo: releaseTime, releaseTime: releaseTime}, time.Now(), cfg)
+
+				Convey("Then the Expires header should be the Date header, as the max-age is 0", func() {
+					So(w.Header().Get("Cache-Control"), ShouldEqual, "public, s-maxage=89, max-age=0")
+					So(w.Header().Get("Expires"), ShouldEqual, w.Header().Get("Date"))
+				})
+			})
+		})
+
+		Convey("When the response is written unmodified", func() {
+			w := httptest.NewRecorder()
+			writeUnmodifiedResponse(ctx, w, newServiceResponse(), map[string]string{})
+
+			Convey("Then the upstream's Age and Date headers should be kept, without an Expires header", func() {
+				So(w.Header().Get("Age"), ShouldEqual, "120")
+				So(w.Header().Get("Date"), ShouldEqual, "Mon, 01 Jan 2024 00:00:00 GMT")
+				So(w.Header().Values("Expires"), ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestWriteResponseWithMaxAgeReleaseEmbargo(t *testing.T) {
+	Convey("Given a release embargo window of 30 seconds", t, func() {
+		ctx := context.Background()
+		cfg := &config.Config{StaleWhileRevalidateSeconds: 30, StaleIfErrorSeconds: -1, EnableMaxAgeCountdown: true, ReleaseEmbargoWindow: 30 * time.Second}
+
+		write := func(cacheControl string, maxAge calculatedMaxAge) http.Header {
+			w := httptest.NewRecorder()
+			header := http.Header{}
+			header.Set("Age", "5")
+			serviceResponse := &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader("body"))}
+			writeResponseWithMaxAge(ctx, w, serviceResponse, ParseCacheControl(cacheControl), maxAge, time.Now(), cfg)
+			return w.Header()
+		}
+
+		Convey("When the page will be released within the window", func() {
+			releaseTime := time.Now().Add(10 * time.Second)
+			maxAge := calculatedMaxAge{seconds: 9, countdownTo: releaseTime, releaseTime: releaseTime}
+
+			Convey("Then the response should not be stored", func() {
+				header := write("", maxAge)
+				So(header.Get("Cache-Control"), ShouldEqual, "no-store")
+				So(header.Get("Expires"), ShouldEqual, header.Get("Date"))
+				So(header.Values("Age"), ShouldBeEmpty)
+			})
+
+			Convey("Then the upstream's other directives should be kept", func() {
+				So(write("Public, must-revalidate, stale-if-error=60", maxAge).Get("Cache-Control"), ShouldEqual, "must-revalidate, no-store")
+			})
+
+			Convey("And a CDN cache header is configured", func() {
+				cfg.CDNCacheHeader = "Surrogate-Control"
+
+				Convey("Then the CDN should not store the response either", func() {
+					header := write("", maxAge)
+					So(header.Get("Surrogate-Control"), ShouldEqual, "no-store")
+					So(header.Get("Cache-Control"), ShouldEqual, "no-store")
+				})
+			})
+		})
+
+		Convey("When the page will be released after the window", func() {
+			releaseTime := time.Now().Add(time.Minute)
+
+			Convey("Then the max-age should count down to the release", func() {
+				So(write("", calculatedMaxAge{seconds: 59, countdownTo: releaseTime, releaseTime: releaseTime}).Get("Cache-Control"), ShouldEqual, "public, s-maxage=59, max-age=59, stale-while-revalidate=30")
+			})
+		})
+
+		Convey("When the page was released within the publish expiry offset", func() {
+			releaseTime := time.Now().Add(-10 * time.Second)
+
+			Convey("Then the max-age should be set as usual", func() {
+				So(write("", calculatedMaxAge{seconds: 10, releaseTime: releaseTime}).Get("Cache-Control"), ShouldEqual, "public, s-maxage=10, max-age=10, stale-while-revalidate=30")
+			})
+		})
+	})
+
+	Convey("Given no release embargo window", t, func() {
+		cfg := &config.Config{ReleaseEmbargoWindow: 0}
+		releaseTime := time.Now().Add(10 * time.Second)
+
+		Convey("Then a page that will be released soon should not be embargoed", func() {
+			So(isInReleaseEmbargo(calculatedMaxAge{seconds: 9, countdownTo: releaseTime, releaseTime: releaseTime}, time.Now(), cfg), ShouldBeFalse)
+		})
+	})
+}
+
+func TestMaxAgeHeadersWithCDNCacheHeader(t *testing.T) {
+	Convey("Given a CDN cache header and a browser cache time", t, func() {
+		cfg := &config.Config{CDNCacheHeader: "Surrogate-Control", CacheTimeBrowser: time.Minute, EnableMaxAgeCountdown: true}
+
+		Convey("When the headers for a max-age longer than the browser cache time are built", func() {
+			headers := maxAgeHeaders(ParseCacheControl(publicString), 900, time.Time{}, 30, -1, time.Now(), cfg)
+
+			Convey("Then the CDN's max-age should be set in the CDN cache header and the browsers' should be capped", func() {
+				So(headers["Surrogate-Control"], ShouldEqual, "max-age=900, stale-while-revalidate=30")
+				So(headers["Cache-Control"], ShouldEqual, "public, max-age=60, stale-while-revalidate=30")
+			})
+
+			Convey("Then the Expires header should match the browsers' max-age", func() {
+				date, err := http.ParseTime(headers["Date"])
+				So(err, ShouldBeNil)
+				expiry, err := http.ParseTime(headers["Expires"])
+				So(err, ShouldBeNil)
+				So(expiry.Sub(date), ShouldEqual, time.Minute)
+			})
+		})
+
+		Convey("When the headers for a max-age counting down to a release are built", func() {
+			releaseTime := time.Now().Add(30 * time.Second).Truncate(time.Second)
+
+			Convey("And the release is sooner than the browser cache time", func() {
+				headers := maxAgeHeaders(ParseCacheControl(publicString), 29, releaseTime, -1, -1, time.Now(), cfg)
+
+				Convey("Then both max-ages should count down to the release", func() {
+					So(headers["Surrogate-Control"], ShouldEqual, "max-age=29")
+					So(headers["Cache-Control"], ShouldEqual, "public, max-age=29")
+					So(headers["Expires"], ShouldEqual, releaseTime.UTC().Format(http.TimeFormat))
+				})
+			})
+
+			Convey("And the release is later than the browser cache time", func() {
+				cfg.CacheTimeBrowser = 10 * time.Second
+				headers := maxAgeHeaders(ParseCacheControl(publicString), 29, releaseTime, -1, -1, time.Now(), cfg)
+
+				Convey("Then the CDN's max-age should count down to the release and the browsers' should be capped", func() {
+					So(headers["Surrogate-Control"], ShouldEqual, "max-age=29")
+					So(headers["Cache-Control"], ShouldEqual, "public, max-age=10")
+					So(headers["Expires"], ShouldNotEqual, releaseTime.UTC().Format(http.TimeFormat))
+				})
+			})
+
+			Convey("And the max-age countdown is disabled", func() {
+				cfg.EnableMaxAgeCountdown = false
+				headers := maxAgeHeaders(ParseCacheControl(publicString), 29, releaseTime, -1, -1, time.Now(), cfg)
+
+				Convey("Then only the CDN's max-age should count down to the release", func() {
+					So(headers["Surrogate-Control"], ShouldEqual, "max-age=29")
+					So(headers["Cache-Control"], ShouldEqual, "public, max-age=0")
+				})
+			})
+		})
+
+		Convey("When the headers for a response with an upstream s-maxage are built", func() {
+			headers := maxAgeHeaders(ParseCacheControl("public, s-maxage=30"), 900, time.Time{}, -1, -1, time.Now(), cfg)
+
+			Convey("Then the s-maxage should be replaced by the CDN cache header", func() {
+				So(headers["Surrogate-Control"], ShouldEqual, "max-age=900")
+				So(headers["Cache-Control"], ShouldEqual, "public, max-age=60")
+			})
+		})
+
+		Convey("When the headers for a private response are built", func() {
+			headers := maxAgeHeaders(ParseCacheControl(privateString), 900, time.Time{}, -1, -1, time.Now(), cfg)
+
+			Convey("Then the CDN cache header should not be set", func() {
+				So(headers, ShouldNotContainKey, "Surrogate-Control")
+				So(headers["Cache-Control"], ShouldEqual, "private, max-age=60")
+			})
+		})
+	})
+
+	Convey("Given no CDN cache header", t, func() {
+		cfg := &config.Config{CacheTimeBrowser: time.Minute, EnableMaxAgeCountdown: true}
+
+		Convey("When the headers are built", func() {
+			headers := maxAgeHeaders(ParseCacheControl(publicString), 900, time.Time{}, -1, -1, time.Now(), cfg)
+
+			Convey("Then the CDN's max-age should be the s-maxage directive and the browsers' should not be capped", func() {
+				So(headers["Cache-Control"], ShouldEqual, "public, s-maxage=900, max-age=900")
+				So(headers, ShouldNotContainKey, "Surrogate-Control")
+				So(headers, ShouldNotContainKey, "CDN-Cache-Control")
+			})
+		})
+	})
+}
+
+func TestStaleIfErrorSeconds(t *testing.T) {
+	Convey("Given a stale-if-error time of a day", t, func() {
+		cfg := &config.Config{StaleIfErrorSeconds: 86400}
+
+		Convey("When the page does not have an upcoming or recent release", func() {
+			Convey("Then stale-if-error should be a day", func() {
+				So(staleIfErrorSeconds(calculatedMaxAge{seconds: 900}, time.Now(), cfg), ShouldEqual, 86400)
+			})
+		})
+
+		Convey("When the page's release time is unknown", func() {
+			Convey("Then stale-if-error should not be set", func() {
+				So(staleIfErrorSeconds(calculatedMaxAge{seconds: 30, releaseTimeUnknown: true}, time.Now(), cfg), ShouldEqual, -1)
+			})
+		})
+
+		Convey("When the max-age counts down to the page's release", func() {
+			releaseTime := time.Now().Add(time.Minute)
+
+			Convey("Then stale-if-error should not be set", func() {
+				So(staleIfErrorSeconds(calculatedMaxAge{seconds: 60, countdownTo: releaseTime, releaseTime: releaseTime}, time.Now(), cfg), ShouldEqual, -1)
+			})
+		})
+
+		Convey("When the page was released within the publish expiry offset", func() {
+			releaseTime := time.Now().Add(-time.Minute)
+
+			Convey("Then stale-if-error should not be set", func() {
+				So(staleIfErrorSeconds(calculatedMaxAge{seconds: 10, releaseTime: releaseTime}, time.Now(), cfg), ShouldEqual, -1)
+			})
+		})
+
+		Convey("When the page will be released after its max-age expires, but within a day", func() {
+			now := time.Now()
+			releaseTime := now.Add(time.Hour)
+
+			Convey("Then stale-if-error should end by the release", func() {
+				So(staleIfErrorSeconds(calculatedMaxAge{seconds: 900, releaseTime: releaseTime}, now, cfg), ShouldEqual, 2700)
+			})
+		})
+
+		Convey("When the page will be released more than a day after its max-age expires", func() {
+			releaseTime := time.Now().Add(48 * time.Hour)
+
+			Convey("Then stale-if-error should be a day", func() {
+				So(staleIfErrorSeconds(calculatedMaxAge{seconds: 900, releaseTime: releaseTime}, time.Now(), cfg), ShouldEqual, 86400)
+			})
+		})
+	})
+
+	Convey("Given no stale-if-error time", t, func() {
+		cfg := &config.Config{StaleIfErrorSeconds: -1}
+
+		Convey("Then stale-if-error should not be set", func() {
+			So(staleIfErrorSeconds(calculatedMaxAge{seconds: 900}, time.Now(), cfg), ShouldEqual, -1)
+		})
+	})
 }
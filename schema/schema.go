@@ -0,0 +1,23 @@
+package schema
+
+import (
+	"github.com/ONSdigital/dp-kafka/v4/avro"
+)
+
+var contentPublished = `{
+  "type": "record",
+  "name": "content-published",
+  "fields": [
+    {"name": "uri", "type": "string", "default": ""},
+    {"name": "data_type", "type": "string", "default": ""},
+    {"name": "collection_id", "type": "string", "default": ""},
+    {"name": "job_id", "type": "string", "default": ""},
+    {"name": "search_index", "type": "string", "default": ""},
+    {"name": "trace_id", "type": "string", "default": ""}
+  ]
+}`
+
+// ContentPublishedEvent is the Avro schema of the events sent by the publishing pipeline when a page is published
+var ContentPublishedEvent = &avro.Schema{
+	Definition: contentPublished,
+}
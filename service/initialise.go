@@ -1,24 +1,29 @@
 package service
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	kafka "github.com/ONSdigital/dp-kafka/v4"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
 	dphttp "github.com/ONSdigital/dp-net/v3/http"
 )
 
 // ExternalServiceList holds the initialiser and initialisation state of external services.
 type ExternalServiceList struct {
-	HealthCheck bool
-	Init        Initialiser
+	HealthCheck   bool
+	KafkaConsumer bool
+	Init          Initialiser
 }
 
 // NewServiceList creates a new service list with the provided initialiser
 func NewServiceList(initialiser Initialiser) *ExternalServiceList {
 	return &ExternalServiceList{
-		HealthCheck: false,
-		Init:        initialiser,
+		HealthCheck:   false,
+		KafkaConsumer: false,
+		Init:          initialiser,
 	}
 }
 
@@ -45,6 +50,21 @@ func (e *ExternalServiceList) GetRequestMiddleware() RequestMiddleware {
 	return e.Init.DoGetRequestMiddleware()
 }
 
+// GetReleaseTimeSource creates the source of the release times used to calculate the max-age
+func (e *ExternalServiceList) GetReleaseTimeSource(cfg *config.Config) (response.ReleaseTimeSource, error) {
+	return e.Init.DoGetReleaseTimeSource(cfg)
+}
+
+// GetKafkaConsumer creates the Kafka consumer of the content published events and sets the KafkaConsumer flag to true
+func (e *ExternalServiceList) GetKafkaConsumer(ctx context.Context, cfg *config.Config) (kafka.IConsumerGroup, error) {
+	consumer, err := e.Init.DoGetKafkaConsumer(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	e.KafkaConsumer = true
+	return consumer, nil
+}
+
 // DoGetHTTPServer creates an HTTP Server with the provided bind address and router
 func (e *Init) DoGetHTTPServer(cfg *config.Config, bindAddr string, router http.Handler) HTTPServer {
 	s := dphttp.NewServer(bindAddr, router)
@@ -67,3 +87,37 @@ func (e *Init) DoGetHealthCheck(cfg *config.Config, buildTime, gitCommit, versio
 func (e *Init) DoGetRequestMiddleware() RequestMiddleware {
 	return &NoOpRequestMiddleware{}
 }
+
+// DoGetReleaseTimeSource creates the chain of release time sources in the configuration
+func (e *Init) DoGetReleaseTimeSource(cfg *config.Config) (response.ReleaseTimeSource, error) {
+	return response.NewReleaseTimeSource(cfg)
+}
+
+// DoGetKafkaConsumer creates a Kafka consumer group for the content published events
+func (e *Init) DoGetKafkaConsumer(ctx context.Context, cfg *config.Config) (kafka.IConsumerGroup, error) {
+	kafkaOffset := kafka.OffsetNewest
+	if cfg.Kafka.OffsetOldest {
+		kafkaOffset = kafka.OffsetOldest
+	}
+
+	cgConfig := &kafka.ConsumerGroupConfig{
+		BrokerAddrs:       cfg.Kafka.Addr,
+		Topic:             cfg.Kafka.ContentPublishedTopic,
+		GroupName:         cfg.Kafka.ContentPublishedGroup,
+		KafkaVersion:      &cfg.Kafka.Version,
+		MinBrokersHealthy: &cfg.Kafka.ConsumerMinBrokersHealthy,
+		NumWorkers:        &cfg.Kafka.NumWorkers,
+		Offset:            &kafkaOffset,
+		OtelEnabled:       &cfg.OtelEnabled,
+	}
+	if cfg.Kafka.SecProtocol == config.KafkaTLSProtocolFlag {
+		cgConfig.SecurityConfig = kafka.GetSecurityConfig(
+			cfg.Kafka.SecCACerts,
+			cfg.Kafka.SecClientCert,
+			cfg.Kafka.SecClientKey,
+			cfg.Kafka.SecSkipVerify,
+		)
+	}
+
+	return kafka.NewConsumerGroup(ctx, cgConfig)
+}
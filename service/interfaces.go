@@ -5,7 +5,9 @@ import (
 	"net/http"
 
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	kafka "github.com/ONSdigital/dp-kafka/v4"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
 )
 
 //go:generate moq -out mock/initialiser.go -pkg mock . Initialiser
@@ -18,6 +20,8 @@ type Initialiser interface {
 	DoGetHTTPServer(cfg *config.Config, bindAddr string, router http.Handler) HTTPServer
 	DoGetHealthCheck(cfg *config.Config, buildTime, gitCommit, version string) (HealthChecker, error)
 	DoGetRequestMiddleware() RequestMiddleware
+	DoGetReleaseTimeSource(cfg *config.Config) (response.ReleaseTimeSource, error)
+	DoGetKafkaConsumer(ctx context.Context, cfg *config.Config) (kafka.IConsumerGroup, error)
 }
 
 // HTTPServer defines the required methods from the HTTP server
@@ -4,7 +4,10 @@
 package mock
 
 import (
+	"context"
+	"github.com/ONSdigital/dp-kafka/v4"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/service"
 	"net/http"
 	"sync"
@@ -26,6 +29,12 @@ var _ service.Initialiser = &InitialiserMock{}
 //			DoGetHealthCheckFunc: func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error) {
 //				panic("mock out the DoGetHealthCheck method")
 //			},
+//			DoGetKafkaConsumerFunc: func(ctx context.Context, cfg *config.Config) (kafka.IConsumerGroup, error) {
+//				panic("mock out the DoGetKafkaConsumer method")
+//			},
+//			DoGetReleaseTimeSourceFunc: func(cfg *config.Config) (response.ReleaseTimeSource, error) {
+//				panic("mock out the DoGetReleaseTimeSource method")
+//			},
 //			DoGetRequestMiddlewareFunc: func() service.RequestMiddleware {
 //				panic("mock out the DoGetRequestMiddleware method")
 //			},
@@ -42,6 +51,12 @@ type InitialiserMock struct {
 	// DoGetHealthCheckFunc mocks the DoGetHealthCheck method.
 	DoGetHealthCheckFunc func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error)
 
+	// DoGetKafkaConsumerFunc mocks the DoGetKafkaConsumer method.
+	DoGetKafkaConsumerFunc func(ctx context.Context, cfg *config.Config) (kafka.IConsumerGroup, error)
+
+	// DoGetReleaseTimeSourceFunc mocks the DoGetReleaseTimeSource method.
+	DoGetReleaseTimeSourceFunc func(cfg *config.Config) (response.ReleaseTimeSource, error)
+
 	// DoGetRequestMiddlewareFunc mocks the DoGetRequestMiddleware method.
 	DoGetRequestMiddlewareFunc func() service.RequestMiddleware
 
@@ -67,12 +82,26 @@ type InitialiserMock struct {
 			// Version is the version argument value.
 			Version string
 		}
+		// DoGetKafkaConsumer holds details about calls to the DoGetKafkaConsumer method.
+		DoGetKafkaConsumer []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Cfg is the cfg argument value.
+			Cfg *config.Config
+		}
+		// DoGetReleaseTimeSource holds details about calls to the DoGetReleaseTimeSource method.
+		DoGetReleaseTimeSource []struct {
+			// Cfg is the cfg argument value.
+			Cfg *config.Config
+		}
 		// DoGetRequestMiddleware holds details about calls to the DoGetRequestMiddleware method.
 		DoGetRequestMiddleware []struct {
 		}
 	}
 	lockDoGetHTTPServer        sync.RWMutex
 	lockDoGetHealthCheck       sync.RWMutex
+	lockDoGetKafkaConsumer     sync.RWMutex
+	lockDoGetReleaseTimeSource sync.RWMutex
 	lockDoGetRequestMiddleware sync.RWMutex
 }
 
@@ -160,6 +189,74 @@ func (mock *InitialiserMock) DoGetHealthCheckCalls() []struct {
 	return calls
 }
 
+// DoGetKafkaConsumer calls DoGetKafkaConsumerFunc.
+func (mock *InitialiserMock) DoGetKafkaConsumer(ctx context.Context, cfg *config.Config) (kafka.IConsumerGroup, error) {
+	if mock.DoGetKafkaConsumerFunc == nil {
+		panic("InitialiserMock.DoGetKafkaConsumerFunc: method is nil but Initialiser.DoGetKafkaConsumer was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Cfg *config.Config
+	}{
+		Ctx: ctx,
+		Cfg: cfg,
+	}
+	mock.lockDoGetKafkaConsumer.Lock()
+	mock.calls.DoGetKafkaConsumer = append(mock.calls.DoGetKafkaConsumer, callInfo)
+	mock.lockDoGetKafkaConsumer.Unlock()
+	return mock.DoGetKafkaConsumerFunc(ctx, cfg)
+}
+
+// DoGetKafkaConsumerCalls gets all the calls that were made to DoGetKafkaConsumer.
+// Check the length with:
+//
+//	len(mockedInitialiser.DoGetKafkaConsumerCalls())
+func (mock *InitialiserMock) DoGetKafkaConsumerCalls() []struct {
+	Ctx context.Context
+	Cfg *config.Config
+} {
+	var calls []struct {
+		Ctx context.Context
+		Cfg *config.Config
+	}
+	mock.lockDoGetKafkaConsumer.RLock()
+	calls = mock.calls.DoGetKafkaConsumer
+	mock.lockDoGetKafkaConsumer.RUnlock()
+	return calls
+}
+
+// DoGetReleaseTimeSource calls DoGetReleaseTimeSourceFunc.
+func (mock *InitialiserMock) DoGetReleaseTimeSource(cfg *config.Config) (response.ReleaseTimeSource, error) {
+	if mock.DoGetReleaseTimeSourceFunc == nil {
+		panic("InitialiserMock.DoGetReleaseTimeSourceFunc: method is nil but Initialiser.DoGetReleaseTimeSource was just called")
+	}
+	callInfo := struct {
+		Cfg *config.Config
+	}{
+		Cfg: cfg,
+	}
+	mock.lockDoGetReleaseTimeSource.Lock()
+	mock.calls.DoGetReleaseTimeSource = append(mock.calls.DoGetReleaseTimeSource, callInfo)
+	mock.lockDoGetReleaseTimeSource.Unlock()
+	return mock.DoGetReleaseTimeSourceFunc(cfg)
+}
+
+// DoGetReleaseTimeSourceCalls gets all the calls that were made to DoGetReleaseTimeSource.
+// Check the length with:
+//
+//	len(mockedInitialiser.DoGetReleaseTimeSourceCalls())
+func (mock *InitialiserMock) DoGetReleaseTimeSourceCalls() []struct {
+	Cfg *config.Config
+} {
+	var calls []struct {
+		Cfg *config.Config
+	}
+	mock.lockDoGetReleaseTimeSource.RLock()
+	calls = mock.calls.DoGetReleaseTimeSource
+	mock.lockDoGetReleaseTimeSource.RUnlock()
+	return calls
+}
+
 // DoGetRequestMiddleware calls DoGetRequestMiddlewareFunc.
 func (mock *InitialiserMock) DoGetRequestMiddleware() service.RequestMiddleware {
 	if mock.DoGetRequestMiddlewareFunc == nil {
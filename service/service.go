@@ -2,9 +2,15 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"sort"
 
+	kafka "github.com/ONSdigital/dp-kafka/v4"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/admin"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/event"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/proxy"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
 	"github.com/ONSdigital/log.go/v2/log"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -14,12 +20,14 @@ import (
 
 // Service contains all the configs, server and clients to run the proxy
 type Service struct {
-	Config      *config.Config
-	Server      HTTPServer
-	Router      *mux.Router
-	Proxy       *proxy.Proxy
-	ServiceList *ExternalServiceList
-	HealthCheck HealthChecker
+	Config          *config.Config
+	Server          HTTPServer
+	Router          *mux.Router
+	Proxy           *proxy.Proxy
+	ReleaseSchedule *response.ReleaseSchedule
+	KafkaConsumer   kafka.IConsumerGroup
+	ServiceList     *ExternalServiceList
+	HealthCheck     HealthChecker
 }
 
 // Run the service
@@ -28,6 +36,11 @@ func Run(ctx context.Context, cfg *config.Config, serviceList *ExternalServiceLi
 
 	log.Info(ctx, "using service configuration", log.Data{"config": cfg})
 
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(ctx, "invalid service configuration", err)
+		return nil, errors.Wrap(err, "invalid service configuration")
+	}
+
 	router := mux.NewRouter()
 
 	var server HTTPServer
@@ -52,17 +65,90 @@ func Run(ctx context.Context, cfg *config.Config, serviceList *ExternalServiceLi
 		return nil, err
 	}
 
-	if err := registerCheckers(ctx, hc); err != nil {
-		return nil, errors.Wrap(err, "unable to register checkers")
+	releaseTimeSource, err := serviceList.GetReleaseTimeSource(cfg)
+	if err != nil {
+		log.Fatal(ctx, "could not instantiate the release time source", err)
+		return nil, errors.Wrap(err, "unable to get the release time source")
+	}
+
+	// The release schedule is checked before any other release time source, so that the release times of the pages
+	// that have been published recently or will be published soon are known without calling the Legacy Cache API
+	var releaseSchedule *response.ReleaseSchedule
+	if cfg.ReleaseSchedule.Enabled {
+		releaseSchedule = response.NewReleaseSchedule(cfg)
+		releaseTimeSource = response.ChainedReleaseTimeSource{releaseSchedule, releaseTimeSource}
+	}
+
+	// The release times sent by the publishing tooling are checked before any other release time source, as they are the
+	// most up to date. They can only be sent if the admin endpoints are enabled.
+	var releaseTimeStore *response.ReleaseTimeStore
+	if cfg.AdminAuthToken != "" {
+		releaseTimeStore = response.NewReleaseTimeStore(cfg)
+		releaseTimeSource = response.ChainedReleaseTimeSource{releaseTimeStore, releaseTimeSource}
 	}
 
 	router.StrictSlash(true).Path("/health").HandlerFunc(hc.Handler)
+	// The admin routes are added to a subrouter that is created before the proxy's catch-all route, as they depend on
+	// the proxy's release time cache
+	var adminRouter *mux.Router
+	if releaseTimeStore != nil {
+		adminRouter = router.NewRoute().Subrouter()
+	}
 	// The proxy needs to be set up after the HealthCheck route has been added to the router: in the Setup method, the
 	// proxy adds a catch-all route, so any other routes added after that one will never be reachable.
-	p := proxy.Setup(ctx, router, cfg)
+	p, err := proxy.Setup(ctx, router, cfg, releaseTimeSource)
+	if err != nil {
+		log.Fatal(ctx, "could not set up the proxy", err)
+		return nil, errors.Wrap(err, "unable to set up the proxy")
+	}
+
+	if adminRouter != nil {
+		admin.Setup(ctx, adminRouter, cfg, releaseTimeStore, p.ReleaseTimeCache(), p.Clock())
+	}
+
+	var consumer kafka.IConsumerGroup
+	if cfg.Kafka.ContentPublishedEnabled {
+		consumer, err = serviceList.GetKafkaConsumer(ctx, cfg)
+		if err != nil {
+			log.Fatal(ctx, "could not instantiate the kafka consumer", err)
+			return nil, errors.Wrap(err, "unable to get the kafka consumer")
+		}
+
+		// The cache is evicted last, so that it cannot be refilled with a release time that is about to be evicted
+		var evicters []event.ReleaseTimeEvicter
+		if releaseTimeStore != nil {
+			evicters = append(evicters, releaseTimeStore)
+		}
+		if releaseSchedule != nil {
+			evicters = append(evicters, releaseSchedule)
+		}
+		evicters = append(evicters, p.ReleaseTimeCache())
+
+		if err := consumer.RegisterHandler(ctx, event.NewContentPublishedHandler(evicters...).Handle); err != nil {
+			log.Fatal(ctx, "could not register the content published handler", err)
+			return nil, errors.Wrap(err, "unable to register the content published handler")
+		}
+
+		consumer.LogErrors(ctx)
+	}
+
+	if err := registerCheckers(ctx, cfg, hc, p, releaseTimeSource, releaseSchedule, consumer); err != nil {
+		return nil, errors.Wrap(err, "unable to register checkers")
+	}
 
 	hc.Start(ctx)
 
+	if consumer != nil {
+		if err := consumer.Start(); err != nil {
+			log.Fatal(ctx, "could not start the kafka consumer", err)
+			return nil, errors.Wrap(err, "unable to start the kafka consumer")
+		}
+	}
+
+	if releaseSchedule != nil {
+		releaseSchedule.Start(ctx)
+	}
+
 	// Run the http server in a new go-routine
 	go func() {
 		if err := server.ListenAndServe(); err != nil {
@@ -71,12 +157,14 @@ func Run(ctx context.Context, cfg *config.Config, serviceList *ExternalServiceLi
 	}()
 
 	return &Service{
-		Config:      cfg,
-		Router:      router,
-		Proxy:       p,
-		HealthCheck: hc,
-		ServiceList: serviceList,
-		Server:      server,
+		Config:          cfg,
+		Router:          router,
+		Proxy:           p,
+		ReleaseSchedule: releaseSchedule,
+		KafkaConsumer:   consumer,
+		HealthCheck:     hc,
+		ServiceList:     serviceList,
+		Server:          server,
 	}, nil
 }
 
@@ -97,12 +185,38 @@ func (svc *Service) Close(ctx context.Context) error {
 			svc.HealthCheck.Stop()
 		}
 
+		// stop consuming the content published events, so that none are left half-handled
+		if svc.ServiceList.KafkaConsumer {
+			if err := svc.KafkaConsumer.StopAndWait(); err != nil {
+				log.Error(ctx, "failed to stop the kafka consumer", err)
+				hasShutdownError = true
+			}
+		}
+
 		// stop any incoming requests before closing any outbound connections
 		if err := svc.Server.Shutdown(ctx); err != nil {
 			log.Error(ctx, "failed to shutdown http server", err)
 			hasShutdownError = true
 		}
 
+		// close the idle connections to the upstream services once no more requests will be proxied
+		if svc.Proxy != nil {
+			svc.Proxy.Close()
+		}
+
+		// stop loading the release schedule, as nothing uses it anymore
+		if svc.ReleaseSchedule != nil {
+			svc.ReleaseSchedule.Stop()
+		}
+
+		// close the kafka consumer, once nothing depends on it anymore
+		if svc.ServiceList.KafkaConsumer {
+			if err := svc.KafkaConsumer.Close(ctx); err != nil {
+				log.Error(ctx, "failed to close the kafka consumer", err)
+				hasShutdownError = true
+			}
+		}
+
 		// TODO: Close other dependencies, in the expected order
 	}()
 
@@ -126,8 +240,55 @@ func (svc *Service) Close(ctx context.Context) error {
 	return nil
 }
 
-func registerCheckers(_ context.Context, _ HealthChecker) (err error) {
-	// TODO: add other health checks here, as per dp-upload-service
+func registerCheckers(ctx context.Context, cfg *config.Config, hc HealthChecker, p *proxy.Proxy, releaseTimeSource response.ReleaseTimeSource, releaseSchedule *response.ReleaseSchedule, consumer kafka.IConsumerGroup) (err error) {
+	hasErrors := false
+
+	for _, upstreamChecker := range newUpstreamCheckers(cfg) {
+		if err = hc.AddCheck(upstreamChecker.client.Name, upstreamChecker.Checker); err != nil {
+			hasErrors = true
+			log.Error(ctx, "error adding check for upstream", err, log.Data{"upstream": upstreamChecker.client.Name})
+		}
+	}
+
+	upstreams := make([]string, 0, len(p.CircuitBreakers()))
+	for upstream := range p.CircuitBreakers() {
+		upstreams = append(upstreams, upstream)
+	}
+	sort.Strings(upstreams)
+
+	for _, upstream := range upstreams {
+		name := fmt.Sprintf("%s circuit breaker", upstream)
+		if err = hc.AddCheck(name, p.CircuitBreakers()[upstream].Checker); err != nil {
+			hasErrors = true
+			log.Error(ctx, "error adding check for circuit breaker", err, log.Data{"upstream": upstream})
+		}
+	}
+
+	for _, circuitBreaker := range response.ReleaseTimeSourceCircuitBreakers(releaseTimeSource) {
+		name := fmt.Sprintf("%s circuit breaker", circuitBreaker.Name())
+		if err = hc.AddCheck(name, circuitBreaker.Checker); err != nil {
+			hasErrors = true
+			log.Error(ctx, "error adding check for circuit breaker", err, log.Data{"release_time_source": circuitBreaker.Name()})
+		}
+	}
+
+	if releaseSchedule != nil {
+		if err = hc.AddCheck(ReleaseScheduleCheckName, releaseSchedule.Checker); err != nil {
+			hasErrors = true
+			log.Error(ctx, "error adding check for the release schedule", err)
+		}
+	}
+
+	if consumer != nil {
+		if err = hc.AddCheck(KafkaConsumerCheckName, consumer.Checker); err != nil {
+			hasErrors = true
+			log.Error(ctx, "error adding check for the kafka consumer", err)
+		}
+	}
+
+	if hasErrors {
+		return errors.New("Error(s) registering checkers for healthcheck")
+	}
 
 	return nil
 }
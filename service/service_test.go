@@ -9,8 +9,12 @@ import (
 	"time"
 
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	kafka "github.com/ONSdigital/dp-kafka/v4"
+	"github.com/ONSdigital/dp-kafka/v4/kafkatest"
 
 	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
+	responsemock "github.com/ONSdigital/dp-legacy-cache-proxy/response/mock"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/service"
 	"github.com/ONSdigital/dp-legacy-cache-proxy/service/mock"
 
@@ -28,6 +32,9 @@ var (
 	errServer      = errors.New("HTTP Server error")
 	errHealthcheck = errors.New("healthCheck error")
 
+	errReleaseTimeSource = errors.New("release time source error")
+	errKafkaConsumer     = errors.New("kafka consumer error")
+
 	bindAddrAny = "localhost:0"
 )
 
@@ -41,6 +48,11 @@ var funcDoGetHTTPServerNil = func(cfg *config.Config, bindAddr string, router ht
 	return nil
 }
 
+// nolint:revive // param names give context here.
+var funcDoGetReleaseTimeSource = func(cfg *config.Config) (response.ReleaseTimeSource, error) {
+	return response.NewGuardedReleaseTimeSource(response.ReleaseTimeSourceLegacyCacheAPI, &responsemock.ReleaseTimeSourceMock{}, cfg.LegacyCacheAPICircuitBreaker), nil
+}
+
 func TestRun(t *testing.T) {
 	Convey("Having a correctly initialised service and set of mocked dependencies", t, func() {
 		cfg, cfgErr := config.Get()
@@ -93,6 +105,7 @@ func TestRun(t *testing.T) {
 			initMock := &mock.InitialiserMock{
 				DoGetHTTPServerFunc:        funcDoGetHTTPServerNil,
 				DoGetHealthCheckFunc:       funcDoGetHealthcheckErr,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
 				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
 			}
 			svcErrors := make(chan error, 1)
@@ -114,6 +127,7 @@ func TestRun(t *testing.T) {
 			initMock := &mock.InitialiserMock{
 				DoGetHTTPServerFunc:        funcDoGetHTTPServer,
 				DoGetHealthCheckFunc:       funcDoGetHealthcheckOk,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
 				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
 			}
 			svcErrors := make(chan error, 1)
@@ -127,7 +141,15 @@ func TestRun(t *testing.T) {
 			})
 
 			Convey("The checkers are registered and the healthcheck and http server started", func() {
-				So(len(hcMock.AddCheckCalls()), ShouldEqual, 0)
+				So(len(hcMock.AddCheckCalls()), ShouldEqual, 8)
+				So(hcMock.AddCheckCalls()[0].Name, ShouldEqual, service.BabbageCheckName)
+				So(hcMock.AddCheckCalls()[1].Name, ShouldEqual, service.ReleaseCalendarCheckName)
+				So(hcMock.AddCheckCalls()[2].Name, ShouldEqual, service.DatasetControllerCheckName)
+				So(hcMock.AddCheckCalls()[3].Name, ShouldEqual, service.LegacyCacheAPICheckName)
+				So(hcMock.AddCheckCalls()[4].Name, ShouldEqual, "babbage circuit breaker")
+				So(hcMock.AddCheckCalls()[5].Name, ShouldEqual, "dataset-controller circuit breaker")
+				So(hcMock.AddCheckCalls()[6].Name, ShouldEqual, "release-calendar circuit breaker")
+				So(hcMock.AddCheckCalls()[7].Name, ShouldEqual, "legacy-cache-api circuit breaker")
 				So(len(initMock.DoGetHTTPServerCalls()), ShouldEqual, 1)
 				So(len(hcMock.StartCalls()), ShouldEqual, 1)
 				//!!! a call needed to stop the server, maybe ?
@@ -150,7 +172,251 @@ func TestRun(t *testing.T) {
 			})
 		})
 
-		/* ADD CODE OR REMOVE: put this code in, if you have Checkers to register
+		Convey("Given that the release time source cannot be created", func() {
+			initMock := &mock.InitialiserMock{
+				DoGetHTTPServerFunc:  funcDoGetHTTPServerNil,
+				DoGetHealthCheckFunc: funcDoGetHealthcheckOk,
+				// nolint:revive // param names give context here.
+				DoGetReleaseTimeSourceFunc: func(cfg *config.Config) (response.ReleaseTimeSource, error) {
+					return nil, errReleaseTimeSource
+				},
+				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
+			}
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			_, err := service.Run(ctx, cfg, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run fails and the http server is not started", func() {
+				So(err, ShouldNotBeNil)
+				So(errors.Cause(err), ShouldEqual, errReleaseTimeSource)
+				So(len(hcMock.StartCalls()), ShouldEqual, 0)
+			})
+		})
+
+		Convey("Given that the release schedule is enabled", func() {
+			initMock := &mock.InitialiserMock{
+				DoGetHTTPServerFunc:        funcDoGetHTTPServer,
+				DoGetHealthCheckFunc:       funcDoGetHealthcheckOk,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
+				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
+			}
+			cfg.ReleaseSchedule.Enabled = true
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			serverWg.Add(1)
+			svc, err := service.Run(ctx, cfg, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run succeeds and the release schedule check is registered last", func() {
+				So(err, ShouldBeNil)
+				So(svc.ReleaseSchedule, ShouldNotBeNil)
+				So(len(hcMock.AddCheckCalls()), ShouldEqual, 9)
+				So(hcMock.AddCheckCalls()[8].Name, ShouldEqual, service.ReleaseScheduleCheckName)
+				serverWg.Wait()
+			})
+
+			Reset(func() {
+				cfg.ReleaseSchedule.Enabled = false
+				if svc != nil {
+					svc.ReleaseSchedule.Stop()
+				}
+			})
+		})
+
+		Convey("Given that the content published consumer is enabled", func() {
+			// nolint:revive // param names give context here.
+			consumerMock := &kafkatest.IConsumerGroupMock{
+				RegisterHandlerFunc: func(ctx context.Context, h kafka.Handler) error { return nil },
+				LogErrorsFunc:       func(ctx context.Context) {},
+				StartFunc:           func() error { return nil },
+				CheckerFunc:         func(ctx context.Context, state *healthcheck.CheckState) error { return nil },
+			}
+			initMock := &mock.InitialiserMock{
+				DoGetHTTPServerFunc:        funcDoGetHTTPServer,
+				DoGetHealthCheckFunc:       funcDoGetHealthcheckOk,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
+				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
+				// nolint:revive // param names give context here.
+				DoGetKafkaConsumerFunc: func(ctx context.Context, cfg *config.Config) (kafka.IConsumerGroup, error) {
+					return consumerMock, nil
+				},
+			}
+			cfg.Kafka.ContentPublishedEnabled = true
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			serverWg.Add(1)
+			_, err := service.Run(ctx, cfg, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then the handler is registered, the consumer started and its check registered last", func() {
+				So(err, ShouldBeNil)
+				So(svcList.KafkaConsumer, ShouldBeTrue)
+				So(len(consumerMock.RegisterHandlerCalls()), ShouldEqual, 1)
+				So(len(consumerMock.StartCalls()), ShouldEqual, 1)
+				So(len(hcMock.AddCheckCalls()), ShouldEqual, 9)
+				So(hcMock.AddCheckCalls()[8].Name, ShouldEqual, service.KafkaConsumerCheckName)
+				serverWg.Wait()
+			})
+
+			Reset(func() {
+				cfg.Kafka.ContentPublishedEnabled = false
+			})
+		})
+
+		Convey("Given that the kafka consumer cannot be created", func() {
+			initMock := &mock.InitialiserMock{
+				DoGetHTTPServerFunc:        funcDoGetHTTPServerNil,
+				DoGetHealthCheckFunc:       funcDoGetHealthcheckOk,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
+				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
+				// nolint:revive // param names give context here.
+				DoGetKafkaConsumerFunc: func(ctx context.Context, cfg *config.Config) (kafka.IConsumerGroup, error) {
+					return nil, errKafkaConsumer
+				},
+			}
+			cfg.Kafka.ContentPublishedEnabled = true
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			_, err := service.Run(ctx, cfg, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run fails and the http server is not started", func() {
+				So(errors.Cause(err), ShouldEqual, errKafkaConsumer)
+				So(svcList.KafkaConsumer, ShouldBeFalse)
+				So(len(hcMock.StartCalls()), ShouldEqual, 0)
+			})
+
+			Reset(func() {
+				cfg.Kafka.ContentPublishedEnabled = false
+			})
+		})
+
+		Convey("Given that the admin endpoints are enabled", func() {
+			initMock := &mock.InitialiserMock{
+				DoGetHTTPServerFunc:        funcDoGetHTTPServer,
+				DoGetHealthCheckFunc:       funcDoGetHealthcheckOk,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
+				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
+			}
+			cfg.AdminAuthToken = "some-token"
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			serverWg.Add(1)
+			svc, err := service.Run(ctx, cfg, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+			So(err, ShouldBeNil)
+			serverWg.Wait()
+
+			Convey("Then the release times endpoint is added before the proxy's catch-all route", func() {
+				var routeNames []string
+				// nolint:revive // param names give context here.
+				_ = svc.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+					if name := route.GetName(); name != "" {
+						routeNames = append(routeNames, name)
+					}
+					return nil
+				})
+				So(routeNames, ShouldResemble, []string{"Admin Release Times", "Admin Release Time Cache Stats", "Proxy Catch-All"})
+			})
+
+			Reset(func() {
+				cfg.AdminAuthToken = ""
+			})
+		})
+
+		Convey("Given that the routing table is not valid", func() {
+			initMock := &mock.InitialiserMock{
+				DoGetHTTPServerFunc:        funcDoGetHTTPServerNil,
+				DoGetHealthCheckFunc:       funcDoGetHealthcheckOk,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
+				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
+			}
+			cfg.RoutingTableFile = "non-existent-routing-table.json"
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			_, err := service.Run(ctx, cfg, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run fails and the http server is not started", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "unable to set up the proxy")
+				So(len(hcMock.StartCalls()), ShouldEqual, 0)
+			})
+
+			Reset(func() {
+				cfg.RoutingTableFile = ""
+			})
+		})
+
+		Convey("Given that the cache policy is not valid", func() {
+			initMock := &mock.InitialiserMock{
+				DoGetHTTPServerFunc:        funcDoGetHTTPServerNil,
+				DoGetHealthCheckFunc:       funcDoGetHealthcheckOk,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
+				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
+			}
+			cfg.CachePolicyFile = "non-existent-cache-policy.json"
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			_, err := service.Run(ctx, cfg, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run fails and the http server is not started", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "unable to set up the proxy")
+				So(len(hcMock.StartCalls()), ShouldEqual, 0)
+			})
+
+			Reset(func() {
+				cfg.CachePolicyFile = ""
+			})
+		})
+
+		Convey("Given that time travel is enabled in production", func() {
+			initMock := &mock.InitialiserMock{
+				DoGetHTTPServerFunc:        funcDoGetHTTPServerNil,
+				DoGetHealthCheckFunc:       funcDoGetHealthcheckOk,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
+				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
+			}
+			cfg.Environment = "production"
+			cfg.EnableTimeTravel = true
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			_, err := service.Run(ctx, cfg, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run fails and the http server is not started", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "unable to set up the proxy")
+				So(len(hcMock.StartCalls()), ShouldEqual, 0)
+			})
+
+			Reset(func() {
+				cfg.Environment = ""
+				cfg.EnableTimeTravel = false
+			})
+		})
+
+		Convey("Given that the release schedule configuration is not valid", func() {
+			initMock := &mock.InitialiserMock{
+				DoGetHTTPServerFunc:        funcDoGetHTTPServerNil,
+				DoGetHealthCheckFunc:       funcDoGetHealthcheckOk,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
+				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
+			}
+			cfg.ReleaseSchedule.Enabled = true
+			cfg.ReleaseSchedule.PollInterval = 0
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			_, err := service.Run(ctx, cfg, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run fails and the http server is not started", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "invalid service configuration")
+				So(len(initMock.DoGetHTTPServerCalls()), ShouldEqual, 0)
+				So(len(hcMock.StartCalls()), ShouldEqual, 0)
+			})
+
+			Reset(func() {
+				cfg.ReleaseSchedule.Enabled = false
+				cfg.ReleaseSchedule.PollInterval = time.Minute
+			})
+		})
+
 		Convey("Given that Checkers cannot be registered", func() {
 			// setup (run before each `Convey` at this scope / indentation):
 			errAddheckFail := errors.New("Error(s) registering checkers for healthcheck")
@@ -164,7 +430,8 @@ func TestRun(t *testing.T) {
 				DoGetHealthCheckFunc: func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error) {
 					return hcMockAddFail, nil
 				},
-				// ADD CODE: add the checkers that you want to register here
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
+				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
 			}
 			svcErrors := make(chan error, 1)
 			svcList := service.NewServiceList(initMock)
@@ -174,19 +441,20 @@ func TestRun(t *testing.T) {
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldResemble, fmt.Sprintf("unable to register checkers: %s", errAddheckFail.Error()))
 				So(svcList.HealthCheck, ShouldBeTrue)
-				// ADD CODE: add code to confirm checkers exist
-				So(len(hcMockAddFail.AddCheckCalls()), ShouldEqual, 0) // ADD CODE: change the '0' to the number of checkers you have registered
+				So(len(hcMockAddFail.AddCheckCalls()), ShouldEqual, 8)
+				So(len(hcMockAddFail.StartCalls()), ShouldEqual, 0)
 			})
 			Reset(func() {
 				// This reset is run after each `Convey` at the same scope (indentation)
 			})
-		})*/
+		})
 
 		Convey("Given that all dependencies are successfully initialised but the http server fails", func() {
 			// setup (run before each `Convey` at this scope / indentation):
 			initMock := &mock.InitialiserMock{
 				DoGetHealthCheckFunc:       funcDoGetHealthcheckOk,
 				DoGetHTTPServerFunc:        funcDoGetFailingHTTPServer,
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
 				DoGetRequestMiddlewareFunc: funcDoGetRequestMiddleware,
 			}
 			svcErrors := make(chan error, 1)
@@ -236,6 +504,10 @@ func TestClose(t *testing.T) {
 			},
 		}
 
+		Reset(func() {
+			cfg.Kafka.ContentPublishedEnabled = false
+		})
+
 		Convey("Closing the service results in all the dependencies being closed in the expected order", func() {
 			// nolint:revive // param names give context here.
 			initMock := &mock.InitialiserMock{
@@ -243,6 +515,7 @@ func TestClose(t *testing.T) {
 				DoGetHealthCheckFunc: func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error) {
 					return hcMock, nil
 				},
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
 				DoGetRequestMiddlewareFunc: func() service.RequestMiddleware { return &service.NoOpRequestMiddleware{} },
 			}
 
@@ -257,6 +530,56 @@ func TestClose(t *testing.T) {
 			So(len(serverMock.ShutdownCalls()), ShouldEqual, 1)
 		})
 
+		Convey("Closing the service stops the kafka consumer before the http server and closes it afterwards", func() {
+			var calls []string
+			// nolint:revive // param names give context here.
+			orderedServerMock := &mock.HTTPServerMock{
+				ListenAndServeFunc: func() error { return nil },
+				ShutdownFunc: func(ctx context.Context) error {
+					calls = append(calls, "server shutdown")
+					return nil
+				},
+			}
+			// nolint:revive // param names give context here.
+			consumerMock := &kafkatest.IConsumerGroupMock{
+				RegisterHandlerFunc: func(ctx context.Context, h kafka.Handler) error { return nil },
+				LogErrorsFunc:       func(ctx context.Context) {},
+				StartFunc:           func() error { return nil },
+				StopAndWaitFunc: func() error {
+					calls = append(calls, "consumer stop")
+					return nil
+				},
+				CloseFunc: func(ctx context.Context, optFuncs ...kafka.OptFunc) error {
+					calls = append(calls, "consumer close")
+					return nil
+				},
+			}
+			// nolint:revive // param names give context here.
+			initMock := &mock.InitialiserMock{
+				DoGetHTTPServerFunc: func(cfg *config.Config, bindAddr string, router http.Handler) service.HTTPServer {
+					return orderedServerMock
+				},
+				DoGetHealthCheckFunc: func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error) {
+					return hcMock, nil
+				},
+				DoGetKafkaConsumerFunc: func(ctx context.Context, cfg *config.Config) (kafka.IConsumerGroup, error) {
+					return consumerMock, nil
+				},
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
+				DoGetRequestMiddlewareFunc: func() service.RequestMiddleware { return &service.NoOpRequestMiddleware{} },
+			}
+			cfg.Kafka.ContentPublishedEnabled = true
+
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			svc, err := service.Run(ctx, cfg, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+			So(err, ShouldBeNil)
+
+			err = svc.Close(context.Background())
+			So(err, ShouldBeNil)
+			So(calls, ShouldResemble, []string{"consumer stop", "server shutdown", "consumer close"})
+		})
+
 		Convey("If services fail to stop, the Close operation tries to close all dependencies and returns an error", func() {
 			// nolint:revive // param names give context here.
 			failingserverMock := &mock.HTTPServerMock{
@@ -273,6 +596,7 @@ func TestClose(t *testing.T) {
 				DoGetHealthCheckFunc: func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error) {
 					return hcMock, nil
 				},
+				DoGetReleaseTimeSourceFunc: funcDoGetReleaseTimeSource,
 				DoGetRequestMiddlewareFunc: func() service.RequestMiddleware { return &service.NoOpRequestMiddleware{} },
 			}
 
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ONSdigital/dp-api-clients-go/v2/health"
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/response"
+	dphttp "github.com/ONSdigital/dp-net/v3/http"
+)
+
+// Names of the health checks of the upstream services
+const (
+	BabbageCheckName           = "babbage"
+	ReleaseCalendarCheckName   = "release-calendar"
+	SearchControllerCheckName  = "search-controller"
+	DatasetControllerCheckName = "dataset-controller"
+	LegacyCacheAPICheckName    = "legacy-cache-api"
+)
+
+// ReleaseScheduleCheckName is the name of the health check reporting how recently the release schedule has been loaded
+const ReleaseScheduleCheckName = "release schedule"
+
+// KafkaConsumerCheckName is the name of the health check reporting the state of the content published consumer
+const KafkaConsumerCheckName = "kafka consumer"
+
+// defaultProbePath is the probe path that the health client requests, falling back to /healthcheck if it is not found
+const defaultProbePath = "/health"
+
+// UpstreamChecker checks the health of an upstream service with the dp-api-clients-go health client. An upstream that
+// has no health endpoint can be probed at another path instead, and a failing upstream that is not critical is only
+// reported as a warning.
+type UpstreamChecker struct {
+	client    *health.Client
+	probePath string
+	critical  bool
+}
+
+// NewUpstreamChecker creates an UpstreamChecker for the upstream service at the given URL
+func NewUpstreamChecker(name, upstreamURL string, hcCfg config.UpstreamHealthCheck) *UpstreamChecker {
+	clienter := dphttp.ClientWithTimeout(nil, hcCfg.Timeout)
+	clienter.SetMaxRetries(0)
+
+	return &UpstreamChecker{
+		client:    health.NewClientWithClienter(name, strings.TrimSuffix(upstreamURL, "/"), clienter),
+		probePath: hcCfg.ProbePath,
+		critical:  hcCfg.Critical,
+	}
+}
+
+// Checker updates the check state with the health of the upstream. A failure is critical if the upstream is configured
+// as critical, otherwise it is a warning.
+func (c *UpstreamChecker) Checker(ctx context.Context, state *healthcheck.CheckState) error {
+	var err error
+	if c.probePath == defaultProbePath {
+		err = c.client.Checker(ctx, state)
+	} else {
+		err = c.probe(ctx, state)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !c.critical && state.Status() == healthcheck.StatusCritical {
+		return state.Update(healthcheck.StatusWarning, state.Message(), state.StatusCode())
+	}
+
+	return nil
+}
+
+// probe sends a GET request to the configured probe path, and updates the check state in the same way as the health
+// client: a 2xx response is OK, a 429 (the status code returned by dp-healthcheck for a WARNING) is a warning and any
+// other response, or no response at all, is critical
+func (c *UpstreamChecker) probe(ctx context.Context, state *healthcheck.CheckState) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.client.URL+c.probePath, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Client.Do(ctx, req)
+	if err != nil {
+		return state.Update(healthcheck.StatusCritical, err.Error(), 0)
+	}
+
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	status := healthcheck.StatusCritical
+	switch {
+	case resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices:
+		status = healthcheck.StatusOK
+	case resp.StatusCode == http.StatusTooManyRequests:
+		status = healthcheck.StatusWarning
+	}
+
+	return state.Update(status, c.client.Name+health.StatusMessage[status], resp.StatusCode)
+}
+
+// newUpstreamCheckers creates the checkers of every upstream service in the configuration. The Search Controller is
+// only checked when it is enabled and the Legacy Cache API only when it is one of the release time sources.
+func newUpstreamCheckers(cfg *config.Config) []*UpstreamChecker {
+	checkers := []*UpstreamChecker{
+		NewUpstreamChecker(BabbageCheckName, cfg.BabbageURL, cfg.BabbageHealthCheck),
+		NewUpstreamChecker(ReleaseCalendarCheckName, cfg.RelCalURL, cfg.RelCalHealthCheck),
+	}
+
+	if cfg.EnableSearchController {
+		checkers = append(checkers, NewUpstreamChecker(SearchControllerCheckName, cfg.SearchControllerURL, cfg.SearchControllerHealthCheck))
+	}
+
+	checkers = append(checkers, NewUpstreamChecker(DatasetControllerCheckName, cfg.DatasetControllerURL, cfg.DatasetControllerHealthCheck))
+
+	if slices.Contains(cfg.ReleaseTimeSources, response.ReleaseTimeSourceLegacyCacheAPI) {
+		checkers = append(checkers, NewUpstreamChecker(LegacyCacheAPICheckName, cfg.LegacyCacheAPIURL, cfg.LegacyCacheAPIHealthCheck))
+	}
+
+	return checkers
+}
@@ -0,0 +1,122 @@
+package service_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/config"
+	"github.com/ONSdigital/dp-legacy-cache-proxy/service"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUpstreamChecker(t *testing.T) {
+	Convey("Given an upstream service", t, func() {
+		var probedPath string
+		statusCode := http.StatusOK
+		upstreamServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			probedPath = r.URL.Path
+			w.WriteHeader(statusCode)
+		}))
+		defer upstreamServer.Close()
+
+		criticalCfg := config.UpstreamHealthCheck{ProbePath: "/health", Timeout: time.Second, Critical: true}
+
+		Convey("When the upstream is healthy", func() {
+			state := healthcheck.NewCheckState(service.BabbageCheckName)
+			err := service.NewUpstreamChecker(service.BabbageCheckName, upstreamServer.URL, criticalCfg).Checker(ctx, state)
+
+			Convey("Then the check is OK and the probe path is requested", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, healthcheck.StatusOK)
+				So(state.Message(), ShouldEqual, "babbage is ok")
+				So(probedPath, ShouldEqual, "/health")
+			})
+		})
+
+		Convey("When a different probe path is configured", func() {
+			cfg := criticalCfg
+			cfg.ProbePath = "/ping"
+			state := healthcheck.NewCheckState(service.BabbageCheckName)
+			err := service.NewUpstreamChecker(service.BabbageCheckName, upstreamServer.URL+"/", cfg).Checker(ctx, state)
+
+			Convey("Then that path is requested", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, healthcheck.StatusOK)
+				So(state.Message(), ShouldEqual, "babbage is ok")
+				So(probedPath, ShouldEqual, "/ping")
+			})
+		})
+
+		Convey("When a different probe path is configured and the upstream is failing", func() {
+			statusCode = http.StatusInternalServerError
+			cfg := criticalCfg
+			cfg.ProbePath = "/ping"
+			state := healthcheck.NewCheckState(service.BabbageCheckName)
+			err := service.NewUpstreamChecker(service.BabbageCheckName, upstreamServer.URL, cfg).Checker(ctx, state)
+
+			Convey("Then the check is critical", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, healthcheck.StatusCritical)
+				So(state.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+			})
+		})
+
+		Convey("When the upstream reports a warning", func() {
+			statusCode = http.StatusTooManyRequests
+			state := healthcheck.NewCheckState(service.BabbageCheckName)
+			err := service.NewUpstreamChecker(service.BabbageCheckName, upstreamServer.URL, criticalCfg).Checker(ctx, state)
+
+			Convey("Then the check is a warning", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, healthcheck.StatusWarning)
+				So(state.StatusCode(), ShouldEqual, http.StatusTooManyRequests)
+			})
+		})
+
+		Convey("When a critical upstream is failing", func() {
+			statusCode = http.StatusInternalServerError
+			state := healthcheck.NewCheckState(service.BabbageCheckName)
+			err := service.NewUpstreamChecker(service.BabbageCheckName, upstreamServer.URL, criticalCfg).Checker(ctx, state)
+
+			Convey("Then the check is critical", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, healthcheck.StatusCritical)
+				So(state.Message(), ShouldEqual, "babbage functionality is unavailable or non-functioning")
+			})
+		})
+
+		Convey("When a non-critical upstream is failing", func() {
+			statusCode = http.StatusInternalServerError
+			cfg := criticalCfg
+			cfg.Critical = false
+			state := healthcheck.NewCheckState(service.LegacyCacheAPICheckName)
+			err := service.NewUpstreamChecker(service.LegacyCacheAPICheckName, upstreamServer.URL, cfg).Checker(ctx, state)
+
+			Convey("Then the check is a warning", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, healthcheck.StatusWarning)
+				So(state.Message(), ShouldEqual, "legacy-cache-api functionality is unavailable or non-functioning")
+			})
+		})
+	})
+
+	Convey("Given an upstream service that is not reachable", t, func() {
+		unreachableServer := httptest.NewServer(http.NotFoundHandler())
+		unreachableServer.Close()
+
+		Convey("When it is checked", func() {
+			state := healthcheck.NewCheckState(service.DatasetControllerCheckName)
+			cfg := config.UpstreamHealthCheck{ProbePath: "/health", Timeout: time.Second, Critical: true}
+			err := service.NewUpstreamChecker(service.DatasetControllerCheckName, unreachableServer.URL, cfg).Checker(ctx, state)
+
+			Convey("Then the check is critical", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, healthcheck.StatusCritical)
+				So(state.Message(), ShouldContainSubstring, "connection refused")
+			})
+		})
+	})
+}